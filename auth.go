@@ -0,0 +1,569 @@
+package steam
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	authServicePath = "/IAuthenticationService/"
+	finalizePath    = "/jwt/finalizelogin"
+
+	authPollAttempts = 30
+)
+
+const (
+	AuthConfirmationUnknown = iota
+	AuthConfirmationNone
+	AuthConfirmationEmailCode
+	AuthConfirmationDeviceCode
+	AuthConfirmationDeviceConfirmation
+	AuthConfirmationEmailConfirmation
+	AuthConfirmationMachineToken
+)
+
+type authRSAKey struct {
+	PublicKeyMod string `json:"publickey_mod"`
+	PublicKeyExp string `json:"publickey_exp"`
+	Timestamp    string `json:"timestamp"`
+}
+
+type authSession struct {
+	ClientID             string  `json:"client_id"`
+	RequestID            string  `json:"request_id"`
+	Interval             float64 `json:"interval"`
+	SteamID              SteamID `json:"steamid,string"`
+	WeakToken            string  `json:"weak_token"`
+	AllowedConfirmations []struct {
+		Type    int    `json:"confirmation_type"`
+		Message string `json:"associated_message"`
+	} `json:"allowed_confirmations"`
+}
+
+func (s *authSession) allows(confirmationType int) bool {
+	for _, confirmation := range s.AllowedConfirmations {
+		if confirmation.Type == confirmationType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *authSession) message(confirmationType int) string {
+	for _, confirmation := range s.AllowedConfirmations {
+		if confirmation.Type == confirmationType {
+			return confirmation.Message
+		}
+	}
+
+	return ""
+}
+
+type authTokens struct {
+	RefreshToken string `json:"refresh_token"`
+	AccessToken  string `json:"access_token"`
+	AccountName  string `json:"account_name"`
+}
+
+type finalizeResponse struct {
+	SteamID      SteamID `json:"steamID,string"`
+	Error        int     `json:"error"`
+	TransferInfo []struct {
+		URL    string            `json:"url"`
+		Params map[string]string `json:"params"`
+	} `json:"transfer_info"`
+}
+
+// callAuthService calls an IAuthenticationService method and decodes the
+// inner "response" object into out.
+func (c *Client) callAuthService(ctx context.Context, httpMethod, method string, params url.Values, out interface{}) error {
+	req := &request{
+		class:  EndpointLogin,
+		method: httpMethod,
+		url:    c.endpoints.webAPI(authServicePath + method + "/v1/"),
+	}
+
+	if httpMethod == http.MethodGet {
+		req.url += "?" + params.Encode()
+	} else {
+		req.form = params
+	}
+
+	resp, err := c.do(ctx, req)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if result := resp.Header.Get("x-eresult"); (result != "" && result != "1") || resp.StatusCode != http.StatusOK {
+		return newSteamError(resp, "")
+	}
+
+	response := struct {
+		Inner interface{} `json:"response"`
+	}{out}
+
+	return json.NewDecoder(resp.Body).Decode(&response)
+}
+
+// authLogin logs in through IAuthenticationService and exchanges the
+// resulting refresh token for community web cookies.
+func (c *Client) authLogin(ctx context.Context) error {
+	if c.credentials.Password == "" {
+		return PasswordEmptyError
+	}
+
+	if err := c.setupCookie(ctx); err != nil {
+		return err
+	}
+
+	var key authRSAKey
+	err := c.callAuthService(ctx, http.MethodGet, "GetPasswordRSAPublicKey", url.Values{
+		"account_name": {c.credentials.Username},
+	}, &key)
+	if err != nil {
+		return err
+	}
+
+	encryptedPassword, err := encryptPassword(c.credentials.Password, key.PublicKeyMod, key.PublicKeyExp)
+	if err != nil {
+		return err
+	}
+
+	var session authSession
+	err = c.callAuthService(ctx, http.MethodPost, "BeginAuthSessionViaCredentials", url.Values{
+		"account_name":         {c.credentials.Username},
+		"encrypted_password":   {encryptedPassword},
+		"encryption_timestamp": {key.Timestamp},
+		"remember_login":       {"true"},
+		"persistence":          {"1"},
+		"website_id":           {"Community"},
+		"device_friendly_name": {c.useragent},
+	}, &session)
+	if err != nil {
+		return err
+	}
+
+	if err = c.confirmAuthSession(ctx, &session); err != nil {
+		return err
+	}
+
+	tokens, err := c.pollAuthSession(ctx, &session)
+	if err != nil {
+		return err
+	}
+
+	sessionID, err := c.finalizeLogin(ctx, tokens.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	c.setSession(&OAuth{
+		ID:           sessionID,
+		DeviceID:     c.deviceID(session.SteamID),
+		SteamID:      session.SteamID,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+
+	return nil
+}
+
+// confirmAuthSession submits a steam guard code when the session asks for one.
+func (c *Client) confirmAuthSession(ctx context.Context, session *authSession) error {
+	if len(session.AllowedConfirmations) == 0 || session.allows(AuthConfirmationNone) {
+		return nil
+	}
+
+	var (
+		code     string
+		codeType int
+		err      error
+	)
+
+	switch {
+	case session.allows(AuthConfirmationDeviceCode):
+		codeType = AuthConfirmationDeviceCode
+		code, err = c.twoFactorCode(ctx)
+	case session.allows(AuthConfirmationEmailCode):
+		codeType = AuthConfirmationEmailCode
+		code, err = c.guardCode(ctx, GuardCodeEmail, session.message(AuthConfirmationEmailCode), RequireEmailCodeError)
+	default:
+		// remaining kinds are approved out of band, polling will pick them up
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return c.callAuthService(ctx, http.MethodPost, "UpdateAuthSessionWithSteamGuardCode", url.Values{
+		"client_id": {session.ClientID},
+		"steamid":   {session.SteamID.ToString()},
+		"code":      {code},
+		"code_type": {strconv.Itoa(codeType)},
+	}, nil)
+}
+
+func (c *Client) pollAuthSession(ctx context.Context, session *authSession) (*authTokens, error) {
+	interval := time.Duration(session.Interval * float64(time.Second))
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	for attempt := 0; attempt < authPollAttempts; attempt++ {
+		var tokens authTokens
+		err := c.callAuthService(ctx, http.MethodPost, "PollAuthSessionStatus", url.Values{
+			"client_id":  {session.ClientID},
+			"request_id": {session.RequestID},
+		}, &tokens)
+		if err != nil {
+			return nil, err
+		}
+
+		if tokens.RefreshToken != "" {
+			return &tokens, nil
+		}
+
+		if err = sleepContext(ctx, interval); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, AuthSessionTimeoutError
+}
+
+// finalizeLogin trades the refresh token for web cookies on every steam
+// domain and returns the community sessionid.
+func (c *Client) finalizeLogin(ctx context.Context, refreshToken string) (string, error) {
+	sessionID, err := c.ensureSessionID()
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.do(ctx, &request{
+		class:  EndpointLogin,
+		method: http.MethodPost,
+		url:    c.endpoints.login(finalizePath),
+		form: url.Values{
+			"nonce":     {refreshToken},
+			"sessionid": {sessionID},
+			"redir":     {c.endpoints.community("/login/home/?goto=")},
+		},
+		referer: c.endpoints.Community + "/",
+	})
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	var response finalizeResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", err
+	}
+
+	if response.Error != 0 || len(response.TransferInfo) == 0 {
+		return "", InvalidSessionError
+	}
+
+	for _, transfer := range response.TransferInfo {
+		params := url.Values{
+			"steamID": {response.SteamID.ToString()},
+		}
+		for k, v := range transfer.Params {
+			params.Set(k, v)
+		}
+
+		resp, err := c.do(ctx, &request{
+			class:  EndpointLogin,
+			method: http.MethodPost,
+			url:    transfer.URL,
+			form:   params,
+		})
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+	}
+
+	return sessionID, nil
+}
+
+// ensureSessionID returns the community sessionid cookie, generating one
+// when steam did not hand it out.
+func (c *Client) ensureSessionID() (string, error) {
+	steamUrl, err := url.Parse(c.endpoints.Community)
+	if err != nil {
+		return "", err
+	}
+
+	for _, cookie := range c.jar.Cookies(steamUrl) {
+		if cookie.Name == "sessionid" {
+			return cookie.Value, nil
+		}
+	}
+
+	buf := make([]byte, 12)
+	if _, err = rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	sessionID := hex.EncodeToString(buf)
+	c.jar.SetCookies(steamUrl, []*http.Cookie{{Name: "sessionid", Value: sessionID}})
+
+	return sessionID, nil
+}
+
+const (
+	accessTokenRenewBefore   = time.Hour
+	accessTokenCheckInterval = time.Minute
+)
+
+type jwtClaims struct {
+	Subject   string `json:"sub"`
+	ExpiresAt int64  `json:"exp"`
+	IssuedAt  int64  `json:"iat"`
+}
+
+func parseJWT(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, InvalidTokenError
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, InvalidTokenError
+	}
+
+	var claims jwtClaims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, InvalidTokenError
+	}
+
+	return &claims, nil
+}
+
+func (c *Client) AccessToken() string {
+	if session := c.getSession(); session != nil {
+		return session.AccessToken
+	}
+	return ""
+}
+
+func (c *Client) RefreshToken() string {
+	if session := c.getSession(); session != nil {
+		return session.RefreshToken
+	}
+	return ""
+}
+
+// AccessTokenExpiry returns the exp claim of the current access token, zero
+// time when there is none.
+func (c *Client) AccessTokenExpiry() time.Time {
+	claims, err := parseJWT(c.AccessToken())
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.Unix(claims.ExpiresAt, 0)
+}
+
+func (c *Client) LoginWithRefreshToken(token string) error {
+	return c.LoginWithRefreshTokenContext(context.Background(), token)
+}
+
+// LoginWithRefreshTokenContext creates a web session from a refresh token
+// obtained by an earlier Login, the password is not needed.
+func (c *Client) LoginWithRefreshTokenContext(ctx context.Context, token string) (err error) {
+	ctx, span := c.tracer.Start(ctx, "steam.LoginWithRefreshToken", "account", c.credentials.Username)
+	defer func() {
+		span.End(err)
+	}()
+
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+
+	err = c.refreshTokenLogin(ctx, token)
+	c.metrics.ObserveLogin(LoginMethodRefreshToken, err)
+	if err != nil {
+		c.logger.Warn("steam refresh token login failed", "account", c.credentials.Username, "error", err)
+		return err
+	}
+
+	c.logger.Info("logged in to steam with refresh token", "account", c.credentials.Username, "steamid", c.GetSteamId())
+	c.saveSession()
+	return nil
+}
+
+func (c *Client) refreshTokenLogin(ctx context.Context, token string) error {
+	claims, err := parseJWT(token)
+	if err != nil {
+		return err
+	}
+
+	if claims.ExpiresAt != 0 && time.Unix(claims.ExpiresAt, 0).Before(c.now()) {
+		return TokenExpiredError
+	}
+
+	steamID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		return InvalidTokenError
+	}
+
+	if err = c.setupCookie(ctx); err != nil {
+		return err
+	}
+
+	sessionID, err := c.finalizeLogin(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	session := &OAuth{
+		ID:           sessionID,
+		DeviceID:     c.deviceID(SteamID(steamID)),
+		SteamID:      SteamID(steamID),
+		RefreshToken: token,
+	}
+
+	return c.renewAccessToken(ctx, session)
+}
+
+func (c *Client) RenewAccessToken() error {
+	return c.RenewAccessTokenContext(context.Background())
+}
+
+// RenewAccessTokenContext asks steam for a fresh access token using the
+// refresh token and updates the steamLoginSecure cookie.
+func (c *Client) RenewAccessTokenContext(ctx context.Context) error {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+
+	session := c.getSession()
+	if session == nil || session.RefreshToken == "" {
+		return InvalidSessionError
+	}
+
+	renewed := *session
+	err := c.renewAccessToken(ctx, &renewed)
+	c.metrics.ObserveSessionRenewal(SessionRenewalAccessToken, err)
+	if err != nil {
+		return err
+	}
+
+	c.logger.Info("steam access token renewed", "account", c.credentials.Username, "expires", c.AccessTokenExpiry())
+	c.saveSession()
+	return nil
+}
+
+func (c *Client) renewAccessToken(ctx context.Context, session *OAuth) error {
+	var tokens authTokens
+	err := c.callAuthService(ctx, http.MethodPost, "GenerateAccessTokenForApp", url.Values{
+		"refresh_token": {session.RefreshToken},
+		"steamid":       {session.SteamID.ToString()},
+		"renewal_type":  {"1"},
+	}, &tokens)
+	if err != nil {
+		return err
+	}
+
+	if tokens.AccessToken == "" {
+		return InvalidTokenError
+	}
+
+	session.AccessToken = tokens.AccessToken
+	if tokens.RefreshToken != "" {
+		session.RefreshToken = tokens.RefreshToken
+	}
+
+	cookie := &http.Cookie{
+		Name:  "steamLoginSecure",
+		Value: session.SteamID.ToString() + "%7C%7C" + session.AccessToken,
+	}
+	for _, host := range []string{c.endpoints.Community, c.endpoints.Store} {
+		u, err := url.Parse(host)
+		if err != nil {
+			return err
+		}
+		c.jar.SetCookies(u, []*http.Cookie{cookie})
+	}
+
+	c.setSession(session)
+	return nil
+}
+
+// accessTokenRenewAt returns when the access token should be renewed:
+// accessTokenRenewBefore it expires, or halfway through shorter lifetimes.
+// ok is false when there is no refresh token to renew it with.
+func (c *Client) accessTokenRenewAt() (renewAt time.Time, ok bool) {
+	session := c.getSession()
+	if session == nil || session.RefreshToken == "" {
+		return time.Time{}, false
+	}
+
+	claims, err := parseJWT(session.AccessToken)
+	if err != nil || claims.ExpiresAt == 0 {
+		return c.now(), true
+	}
+
+	before := accessTokenRenewBefore
+	if claims.IssuedAt != 0 {
+		if half := time.Duration(claims.ExpiresAt-claims.IssuedAt) * time.Second / 2; half < before {
+			before = half
+		}
+	}
+
+	return time.Unix(claims.ExpiresAt, 0).Add(-before), true
+}
+
+// keepAccessToken renews the access token before it expires until the
+// client is destroyed, independently of the session check.
+func (c *Client) keepAccessToken() {
+	var wait time.Duration
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-c.sessionSet:
+			timer.Stop()
+		case <-c.ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		wait = accessTokenCheckInterval
+		renewAt, ok := c.accessTokenRenewAt()
+		if !ok {
+			continue
+		}
+
+		if until := renewAt.Sub(c.now()); until > 0 {
+			if until < wait {
+				wait = until
+			}
+			continue
+		}
+
+		if err := c.RenewAccessTokenContext(c.ctx); err != nil {
+			c.logger.Warn("unable to renew access token", "error", err)
+			continue
+		}
+		wait = 0
+	}
+}
@@ -0,0 +1,29 @@
+package steam_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zergu1ar/steam"
+)
+
+func TestAccessTokenRenewedWithoutSessionCheck(t *testing.T) {
+	srv := newServer(t)
+	srv.AccessTokenTTL = 2 * time.Second
+	addBot(srv, "bot")
+
+	client := login(t, srv, "bot", steam.WithSessionCheck(0))
+	token := client.AccessToken()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for client.AccessToken() == token {
+		if time.Now().After(deadline) {
+			t.Fatal("access token not renewed")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if _, err := client.GetConfirmations(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,37 +2,67 @@ package steam
 
 import (
 	"context"
+	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
+	"sync"
 	"time"
 )
 
 const (
-	baseUrl          = "https://steamcommunity.com"
-	loginUrl         = "https://steamcommunity.com/login"
-	doLoginUrl       = "https://steamcommunity.com/login/dologin"
-	rsaUrl           = "https://steamcommunity.com/login/getrsakey"
+	loginPath        = "/login"
+	doLoginPath      = "/login/dologin"
+	rsaPath          = "/login/getrsakey"
+	captchaPath      = "/login/rendercaptcha/?gid="
 	defaultUseragent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/51.0.2704.103 Safari/537.36"
 
 	LanguageEng = "english"
 	LanguageRus = "russian"
 
 	confirmationDelay = 3
+
+	defaultSessionCheckInterval = 10 * time.Minute
 )
 
 type Client struct {
-	ctx          context.Context
-	client       *http.Client
-	session      *OAuth
+	ctx       context.Context
+	client    *http.Client
+	endpoints Endpoints
+	mu        sync.RWMutex
+	loginMu   sync.Mutex
+	session   *OAuth
+	// sessionSet wakes keepAccessToken when the session is replaced
+	sessionSet   chan struct{}
 	useragent    string
 	credentials  *Credentials
 	apiKey       string
 	timeDiff     int64
 	language     string
-	Destroy      func()
-	requestQueue map[string]chan RequestItem
+	logger       Logger
+	metrics      Metrics
+	tracer       Tracer
+	now          func() time.Time
+	timeSync     bool
+	retryPolicy  RetryPolicy
+	sessionStore SessionStore
+
+	confirmPolicy ConfirmPolicy
+
+	guardCodeProvider GuardCodeProvider
+
+	sessionCheckInterval time.Duration
+	onSessionExpired     func()
+	onSessionRenewed     func()
+	Destroy              func()
+	limiter              *RateLimiter
+	rateGroup            *RateLimitGroup
+	egress               string
+	proxies              *ProxyPool
+	proxy                *proxySelector
+	jar                  *sessionJar
+	optionErr            error
 }
 
 type Credentials struct {
@@ -40,8 +70,13 @@ type Credentials struct {
 	Password       string
 	SharedSecret   string
 	IdentitySecret string
+	// DeviceID is the device_id of the mobile authenticator, e.g. from its
+	// maFile. It is derived from the steam id when empty.
+	DeviceID string
 }
 
+// Deprecated: confirmation requests are no longer queued, they are throttled
+// by the EndpointMobileConf rate limit. The types are unused.
 type (
 	RequestItem struct {
 		Url          string
@@ -57,53 +92,148 @@ type (
 	}
 )
 
-func NewClient(client *http.Client, useragent string, language string, credentials *Credentials) (*Client, error) {
-	if useragent == "" {
-		useragent = defaultUseragent
+// String keeps the secrets out of logs and error messages.
+func (c Credentials) String() string {
+	return "{Username:" + c.Username + " Password:" + redacted + " SharedSecret:" + redacted + " IdentitySecret:" + redacted + "}"
+}
+
+func NewClient(credentials *Credentials, opts ...Option) (*Client, error) {
+	if err := validateCredentials(credentials); err != nil {
+		return nil, err
+	}
+
+	steamClient := &Client{
+		client:      new(http.Client),
+		endpoints:   DefaultEndpoints,
+		useragent:   defaultUseragent,
+		credentials: credentials,
+		language:    LanguageEng,
+		logger:      nopLogger{},
+		metrics:     nopMetrics{},
+		tracer:      nopTracer{},
+		now:         time.Now,
+		timeSync:    true,
+		retryPolicy: DefaultRetryPolicy,
+		sessionSet:  make(chan struct{}, 1),
+
+		sessionCheckInterval: defaultSessionCheckInterval,
+		confirmPolicy: ConfirmPolicy{
+			MaxAttempts: DefaultConfirmAttempts,
+			Interval:    DefaultConfirmInterval,
+		},
 	}
-	if language == "" {
-		language = LanguageEng
+
+	for _, opt := range opts {
+		opt(steamClient)
 	}
 
-	if err := validateCredentials(credentials); err != nil {
+	if steamClient.optionErr != nil {
+		return nil, steamClient.optionErr
+	}
+
+	// work on a copy, the jar and transport are replaced below
+	httpClient := http.Client{}
+	if steamClient.client != nil {
+		httpClient = *steamClient.client
+	}
+	steamClient.client = &httpClient
+
+	if err := steamClient.setupProxy(); err != nil {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	jar := steamClient.client.Jar
+	if jar == nil {
+		jar, _ = cookiejar.New(nil)
+	}
+	steamClient.jar = &sessionJar{jar: jar}
+	steamClient.client.Jar = steamClient.jar
 
-	queue := make(map[string]chan RequestItem)
-	queue["confirmation"] = make(chan RequestItem, 1000)
+	if steamClient.useragent == "" {
+		steamClient.useragent = defaultUseragent
+	}
+	if steamClient.language == "" {
+		steamClient.language = LanguageEng
+	}
+	if steamClient.logger == nil {
+		steamClient.logger = nopLogger{}
+	}
+	if steamClient.metrics == nil {
+		steamClient.metrics = nopMetrics{}
+	}
+	if steamClient.tracer == nil {
+		steamClient.tracer = nopTracer{}
+	}
+	if steamClient.now == nil {
+		steamClient.now = time.Now
+	}
+	if steamClient.limiter == nil {
+		steamClient.limiter = NewRateLimiter(DefaultRateLimits)
+	}
 
-	steamClient := &Client{
-		ctx:          ctx,
-		Destroy:      cancel,
-		client:       client,
-		useragent:    useragent,
-		credentials:  credentials,
-		language:     language,
-		requestQueue: queue,
+	if steamClient.timeSync {
+		timeTip, err := steamClient.queryTimeTip(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("steam time sync: %w", err)
+		}
+		steamClient.timeDiff = timeTip.Time - steamClient.now().Unix()
+		steamClient.logger.Debug("steam time synchronized", "diff", steamClient.timeDiff)
 	}
 
-	timeTip, err := GetTimeTip()
-	if err != nil {
-		log.Fatal(err)
+	steamClient.ctx, steamClient.Destroy = context.WithCancel(context.Background())
+
+	if steamClient.restoreStoredSession(steamClient.ctx) {
+		steamClient.logger.Info("restored stored steam session", "account", credentials.Username, "steamid", steamClient.GetSteamId())
 	}
-	steamClient.timeDiff = timeTip.Time - time.Now().Unix()
 
-	// start goroutines to perform requests
-	go steamClient.confirmationReqWorker(confirmationDelay)
 	go steamClient.checkSession()
+	go steamClient.keepAccessToken()
 
 	return steamClient, nil
 }
 
 func (c *Client) getTimeDiff() int64 {
-	return time.Now().Unix() + c.timeDiff
+	return c.now().Unix() + c.timeDiff
 }
 
 func (c *Client) GetSteamId() SteamID {
-	if c.session != nil {
-		return c.session.SteamID
+	if session := c.getSession(); session != nil {
+		return session.SteamID
 	}
 	return SteamID(0)
 }
+
+func (c *Client) getSession() *OAuth {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.session
+}
+
+func (c *Client) setSession(session *OAuth) {
+	c.mu.Lock()
+	c.session = session
+	c.mu.Unlock()
+
+	select {
+	case c.sessionSet <- struct{}{}:
+	default:
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RateLimiterStats reports how much each endpoint class has been throttled.
+func (c *Client) RateLimiterStats() map[EndpointClass]LimiterStats {
+	return c.limiter.Stats()
+}
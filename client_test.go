@@ -0,0 +1,15 @@
+package steam_test
+
+import "testing"
+
+func TestPlainMethodsAfterDestroy(t *testing.T) {
+	srv := newServer(t)
+	addBot(srv, "bot")
+
+	client := login(t, srv, "bot")
+	client.Destroy()
+
+	if _, err := client.GetConfirmations(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,35 +2,42 @@ package steam
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/PuerkitoBio/goquery"
 )
 
 const (
-	confirmationUrl = "https://steamcommunity.com/mobileconf/"
-	AnswerAllow     = "allow"
-	AnswerDeny      = "deny"
+	confirmationPath = "/mobileconf/"
+	AnswerAllow      = "allow"
+	AnswerDeny       = "deny"
+)
+
+const (
+	ConfirmationTypeTrade         = 2
+	ConfirmationTypeMarketListing = 3
 )
 
 type Confirmation struct {
 	ID        uint64
 	Key       uint64
+	Type      uint8
 	Title     string
 	Receiving string
 	Since     string
-	OfferID   uint64
+	// OfferID is the trade offer id for trades and the listing id for
+	// market listings.
+	OfferID uint64
 }
 
-func (confirmation *Confirmation) Answer(client *Client, answer string) error {
-	return client.AnswerConfirmation(confirmation, answer)
+func (confirmation *Confirmation) Answer(client ConfirmationService, answer string) error {
+	return client.AnswerConfirmationContext(context.Background(), confirmation, answer)
 }
 
 type ConfirmationAnswerResponse struct {
@@ -38,47 +45,37 @@ type ConfirmationAnswerResponse struct {
 	Message string `json:"message"`
 }
 
-func (c *Client) confirmationReqWorker(delay int64) {
-	for {
-		select {
-		case req := <-c.requestQueue["confirmation"]:
-			req.ResponseChan <- c.execConfirmationRequest(req.Url, req.Body, req.Params, nil)
-			time.Sleep(time.Second * time.Duration(delay))
-		case <-c.ctx.Done():
-			close(c.requestQueue["confirmation"])
-			return
-		}
-	}
+func (c *Client) GetConfirmations() ([]*Confirmation, error) {
+	return c.GetConfirmationsContext(context.Background())
 }
 
-func (c *Client) GetConfirmations() ([]*Confirmation, error) {
-	req := RequestItem{
-		Url: "conf?",
-		Params: url.Values{
-			"tag": {"confirmation"},
-		},
-		ResponseChan: make(chan RequestResponse),
-	}
-	c.requestQueue["confirmation"] <- req
+func (c *Client) GetConfirmationsContext(ctx context.Context) (_ []*Confirmation, err error) {
+	ctx, span := c.tracer.Start(ctx, "steam.GetConfirmations")
+	defer func() {
+		span.End(err)
+	}()
 
-	resp := <-req.ResponseChan
-	close(req.ResponseChan)
-	if resp.Error != nil {
-		return nil, resp.Error
+	body, _, err := c.execConfirmationRequest(ctx, "conf?", url.Values{
+		"tag": {"confirmation"},
+	}, nil, false)
+	if err != nil {
+		return nil, err
 	}
 
-	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body))
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 
-	entries := doc.Find(".mobileconf_list_entry")
-	if entries == nil {
-		return nil, ConfirmationsNotFoundError
+	// an empty queue has mobileconf_empty instead of the list, any other
+	// page is not the confirmations page
+	if doc.Find("#mobileconf_list, #mobileconf_empty").Length() == 0 {
+		return nil, InvalidSessionError
 	}
 
+	entries := doc.Find(".mobileconf_list_entry")
 	descriptions := doc.Find(".mobileconf_list_entry_description")
-	if descriptions == nil {
+	if descriptions.Length() != entries.Length() {
 		return nil, ConfirmationsDescriptionNotFoundError
 	}
 
@@ -90,6 +87,9 @@ func (c *Client) GetConfirmations() ([]*Confirmation, error) {
 				confirmation.ID, _ = strconv.ParseUint(attr.Val, 10, 64)
 			} else if attr.Key == "data-key" {
 				confirmation.Key, _ = strconv.ParseUint(attr.Val, 10, 64)
+			} else if attr.Key == "data-type" {
+				confType, _ := strconv.ParseUint(attr.Val, 10, 8)
+				confirmation.Type = uint8(confType)
 			} else if attr.Key == "data-creator" {
 				confirmation.OfferID, _ = strconv.ParseUint(attr.Val, 10, 64)
 			}
@@ -114,22 +114,28 @@ func (c *Client) GetConfirmations() ([]*Confirmation, error) {
 		confirmations = append(confirmations, confirmation)
 	}
 
+	c.metrics.SetPendingConfirmations(c.credentials.Username, len(confirmations))
+	span.SetAttributes("confirmations", len(confirmations))
 	return confirmations, nil
 }
 
-func (c *Client) execConfirmationRequest(uri string, body io.Reader, params url.Values, values map[string]interface{}) RequestResponse {
-	key, err := GenerateConfirmationCode(c.credentials.IdentitySecret, "confirmation", c.getTimeDiff())
+// execConfirmationRequest signs and sends a mobileconf request, unsafe is set
+// for requests that act on a confirmation.
+func (c *Client) execConfirmationRequest(ctx context.Context, uri string, params url.Values, values map[string]interface{}, unsafe bool) ([]byte, int, error) {
+	now := c.getTimeDiff()
+	key, err := GenerateConfirmationCode(c.credentials.IdentitySecret, params.Get("tag"), now)
 	if err != nil {
-		return RequestResponse{
-			Error:  err,
-			Body:   []byte(""),
-			Status: http.StatusBadRequest,
-		}
+		return nil, http.StatusBadRequest, err
+	}
+
+	session := c.getSession()
+	if session == nil {
+		return nil, http.StatusUnauthorized, InvalidSessionError
 	}
 
-	params.Set("p", c.session.DeviceID)
-	params.Set("a", c.session.SteamID.ToString())
-	params.Set("t", strconv.FormatInt(c.getTimeDiff(), 10))
+	params.Set("p", session.DeviceID)
+	params.Set("a", session.SteamID.ToString())
+	params.Set("t", strconv.FormatInt(now, 10))
 	params.Set("m", "android")
 	params.Set("k", key)
 
@@ -142,59 +148,73 @@ func (c *Client) execConfirmationRequest(uri string, body io.Reader, params url.
 		}
 	}
 
-	respBody := []byte("")
-	resp, err := c.client.Get(confirmationUrl + uri + params.Encode())
+	resp, err := c.do(ctx, &request{
+		class:  EndpointMobileConf,
+		method: http.MethodGet,
+		url:    c.endpoints.community(confirmationPath) + uri + params.Encode(),
+		unsafe: unsafe,
+	})
 	if err != nil {
-		return RequestResponse{
-			Error:  err,
-			Body:   respBody,
-			Status: http.StatusBadRequest,
-		}
+		return nil, http.StatusBadRequest, err
 	}
 
-	respBody, err = ioutil.ReadAll(resp.Body)
 	defer resp.Body.Close()
 
-	return RequestResponse{
-		Error:  err,
-		Body:   respBody,
-		Status: resp.StatusCode,
+	// steam sends requests without a session to the login page
+	if resp.Request != nil && strings.HasPrefix(resp.Request.URL.Path, "/login") {
+		return nil, http.StatusUnauthorized, InvalidSessionError
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, newSteamError(resp, "")
 	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	return body, resp.StatusCode, err
 }
 
 func (c *Client) AnswerConfirmation(confirmation *Confirmation, answer string) error {
+	return c.AnswerConfirmationContext(context.Background(), confirmation, answer)
+}
+
+func (c *Client) AnswerConfirmationContext(ctx context.Context, confirmation *Confirmation, answer string) (err error) {
+	ctx, span := c.tracer.Start(ctx, "steam.AnswerConfirmation",
+		"confirmation_id", confirmation.ID,
+		"offer_id", confirmation.OfferID,
+		"answer", answer,
+	)
+	defer func() {
+		span.End(err)
+	}()
+
 	op := map[string]interface{}{
 		"op":  answer,
 		"cid": confirmation.ID,
 		"ck":  confirmation.Key,
 	}
 
-	req := RequestItem{
-		Url:  "ajaxop?",
-		Body: nil,
-		Params: url.Values{
-			"tag": {answer},
-		},
-		ResponseChan: make(chan RequestResponse),
-		Values:       op,
-	}
-
-	c.requestQueue["confirmation"] <- req
-
-	resp := <-req.ResponseChan
-
-	if resp.Error != nil {
-		return resp.Error
+	body, status, err := c.execConfirmationRequest(ctx, "ajaxop?", url.Values{
+		"tag": {answer},
+	}, op, true)
+	if err != nil {
+		return err
 	}
 
 	var response ConfirmationAnswerResponse
-	if err := json.Unmarshal(resp.Body, &response); err != nil {
+	if err := json.Unmarshal(body, &response); err != nil {
 		return err
 	}
 
 	if !response.Success {
-		return errors.New(response.Message)
+		c.logger.Warn("confirmation answer rejected", "confirmation", confirmation.ID, "answer", answer, "message", response.Message)
+		return &SteamError{
+			Result:     EResultFail,
+			StatusCode: status,
+			Endpoint:   confirmationPath + "ajaxop",
+			Message:    response.Message,
+		}
 	}
 
+	c.logger.Info("confirmation answered", "confirmation", confirmation.ID, "offer", confirmation.OfferID, "answer", answer)
 	return nil
 }
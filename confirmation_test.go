@@ -0,0 +1,59 @@
+package steam_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zergu1ar/steam"
+	"github.com/zergu1ar/steam/steamtest"
+)
+
+func TestAnswerConfirmation(t *testing.T) {
+	srv := newServer(t)
+	bot := addBot(srv, "bot")
+	srv.AddConfirmation(steamtest.Confirmation{Owner: bot.SteamID, Title: "Trade"})
+
+	client := login(t, srv, "bot")
+	confirmations, err := client.GetConfirmations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(confirmations) != 1 || confirmations[0].Title != "Trade" {
+		t.Fatalf("confirmations %+v", confirmations)
+	}
+
+	// ajaxop is signed with the answer as tag, not "confirmation"
+	if err = client.AnswerConfirmation(confirmations[0], steam.AnswerAllow); err != nil {
+		t.Fatal(err)
+	}
+
+	if pending := srv.Confirmations(bot.SteamID); len(pending) != 0 {
+		t.Fatalf("pending %+v", pending)
+	}
+}
+
+func TestGetConfirmationsFailures(t *testing.T) {
+	srv := newServer(t)
+	bot := addBot(srv, "bot")
+	srv.AddConfirmation(steamtest.Confirmation{Owner: bot.SteamID})
+	client := login(t, srv, "bot")
+
+	srv.Fail("/mobileconf/conf", steamtest.Failure{Status: 503, Times: -1})
+	confirmations, err := client.GetConfirmations()
+	if !errors.Is(err, steam.EResultServiceUnavailable) {
+		t.Fatalf("got %d confirmations, %v, want a 503 error", len(confirmations), err)
+	}
+
+	srv.ClearFailures()
+	srv.Fail("/mobileconf/conf", steamtest.Failure{Status: 200, Body: "<html><body>Sign In</body></html>", Times: -1})
+	if _, err = client.GetConfirmations(); !errors.Is(err, steam.InvalidSessionError) {
+		t.Fatalf("unknown page: got %v, want %v", err, steam.InvalidSessionError)
+	}
+
+	srv.ClearFailures()
+	srv.ExpireSessions("bot")
+	if _, err = client.GetConfirmations(); !errors.Is(err, steam.InvalidSessionError) {
+		t.Fatalf("expired session: got %v, want %v", err, steam.InvalidSessionError)
+	}
+}
@@ -0,0 +1,60 @@
+package steam
+
+import "strings"
+
+const (
+	communityHost = "https://steamcommunity.com"
+	webAPIHost    = "https://api.steampowered.com"
+	storeHost     = "https://store.steampowered.com"
+	loginHost     = "https://login.steampowered.com"
+)
+
+// Endpoints holds the base URLs of the Steam hosts used by Client.
+// Point them at a local server (e.g. httptest) to run against a stand-in.
+type Endpoints struct {
+	Community string
+	WebAPI    string
+	Store     string
+	Login     string
+}
+
+var DefaultEndpoints = Endpoints{
+	Community: communityHost,
+	WebAPI:    webAPIHost,
+	Store:     storeHost,
+	Login:     loginHost,
+}
+
+func (e Endpoints) withDefaults() Endpoints {
+	if e.Community == "" {
+		e.Community = DefaultEndpoints.Community
+	}
+	if e.WebAPI == "" {
+		e.WebAPI = DefaultEndpoints.WebAPI
+	}
+	if e.Store == "" {
+		e.Store = DefaultEndpoints.Store
+	}
+	if e.Login == "" {
+		e.Login = DefaultEndpoints.Login
+	}
+
+	e.Community = strings.TrimRight(e.Community, "/")
+	e.WebAPI = strings.TrimRight(e.WebAPI, "/")
+	e.Store = strings.TrimRight(e.Store, "/")
+	e.Login = strings.TrimRight(e.Login, "/")
+
+	return e
+}
+
+func (e Endpoints) community(path string) string {
+	return e.Community + path
+}
+
+func (e Endpoints) webAPI(path string) string {
+	return e.WebAPI + path
+}
+
+func (e Endpoints) login(path string) string {
+	return e.Login + path
+}
@@ -1,17 +1,142 @@
 package steam
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
 
 var (
+	CredentialsEmptyError                 = errors.New("credentials are empty")
 	UsernameEmptyError                    = errors.New("username is empty")
 	PasswordEmptyError                    = errors.New("password is empty")
 	InvalidCredentialsError               = errors.New("invalid username or password")
+	RequireEmailCodeError                 = errors.New("require email steam guard code")
+	InvalidTokenError                     = errors.New("invalid token")
+	TokenExpiredError                     = errors.New("token expired")
+	AuthSessionTimeoutError               = errors.New("auth session was not confirmed in time")
 	RequireTwoFactorError                 = errors.New("require two-factor auth")
 	InvalidSessionError                   = errors.New("invalid session")
+	SessionNotFoundError                  = errors.New("session not found")
+	SessionDecryptError                   = errors.New("unable to decrypt session")
+	UnsupportedSessionVersionError        = errors.New("unsupported session version")
 	ApiKeyNotFoundError                   = errors.New("api key not found")
 	ApiAccessDeniedError                  = errors.New("access denied to steam web api")
 	ConfirmationsNotFoundError            = errors.New("can't find confirmation")
 	ConfirmationsDescriptionNotFoundError = errors.New("can't find confirmation description")
 	ErrReceiptMatch                       = errors.New("unable to match items in trade receipt")
 	CannotFindTradeOfferInfoError         = errors.New("unable to match data from trade offer url")
+	PollDataNotFoundError                 = errors.New("poll data not found")
+	InvalidPollIntervalError              = errors.New("poll interval must be positive")
+
+	ProxyPoolEmptyError   = errors.New("proxy pool is empty")
+	UnsupportedProxyError = errors.New("unsupported proxy scheme")
+	ProxyTransportError   = errors.New("proxy requires an *http.Transport")
+
+	ErrTradeOfferAccessDenied = errors.New("access denied to send trade offer")
+	ErrTargetCannotTrade      = errors.New("trade partner cannot trade")
+	ErrItemsNoLongerAvailable = errors.New("items are no longer available")
+	ErrTradeOfferLimitReached = errors.New("too many trade offers")
+	ErrTradeHold              = errors.New("account is on trade hold")
 )
+
+// CaptchaRequiredError is returned by LegacyLogin when steam wants a captcha
+// solved and no GuardCodeProvider is set. Retry with LegacyLoginWithCaptcha.
+type CaptchaRequiredError struct {
+	GID string
+	URL string
+}
+
+func (e *CaptchaRequiredError) Error() string {
+	return fmt.Sprintf("captcha required: %s", e.URL)
+}
+
+// OfferConfirmationError is returned by ConfirmSentOffer when the
+// offer was sent but its mobile confirmation could not be accepted. Err is
+// ConfirmationsNotFoundError when steam never listed it.
+type OfferConfirmationError struct {
+	OfferID  uint64
+	Attempts int
+	Err      error
+}
+
+func (e *OfferConfirmationError) Error() string {
+	return fmt.Sprintf("trade offer %d not confirmed after %d attempts: %v", e.OfferID, e.Attempts, e.Err)
+}
+
+func (e *OfferConfirmationError) Unwrap() error {
+	return e.Err
+}
+
+// SteamError is returned when steam answers a request with a failure. Use
+// errors.Is with an EResult, InvalidSessionError, InvalidCredentialsError or
+// RequireTwoFactorError to tell causes apart; HTTP 429 matches
+// EResultRateLimitExceeded.
+type SteamError struct {
+	Result     EResult
+	StatusCode int
+	Endpoint   string
+	Message    string
+	// Cause is a sentinel such as ErrItemsNoLongerAvailable when the
+	// message could be classified, nil otherwise.
+	Cause error
+}
+
+func newSteamError(resp *http.Response, message string) *SteamError {
+	e := &SteamError{
+		Result:     parseEResult(resp.Header.Get("x-eresult")),
+		StatusCode: resp.StatusCode,
+		Message:    message,
+	}
+
+	if resp.Request != nil && resp.Request.URL != nil {
+		e.Endpoint = resp.Request.URL.Path
+	}
+
+	if e.Result == EResultInvalid {
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			e.Result = EResultRateLimitExceeded
+		case resp.StatusCode == http.StatusUnauthorized:
+			e.Result = EResultNotLoggedOn
+		case resp.StatusCode == http.StatusForbidden:
+			e.Result = EResultAccessDenied
+		case resp.StatusCode >= http.StatusInternalServerError:
+			e.Result = EResultServiceUnavailable
+		default:
+			e.Result = EResultFail
+		}
+	}
+
+	return e
+}
+
+func (e *SteamError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = e.Result.String()
+	}
+
+	return fmt.Sprintf("steam %s: %s (eresult %d, http %d)", e.Endpoint, message, int(e.Result), e.StatusCode)
+}
+
+func (e *SteamError) Unwrap() error {
+	return e.Cause
+}
+
+func (e *SteamError) Is(target error) bool {
+	switch target {
+	case InvalidSessionError:
+		return e.Result == EResultNotLoggedOn || e.Result == EResultLogonSessionReplaced
+	case InvalidCredentialsError:
+		return e.Result == EResultInvalidPassword
+	case RequireTwoFactorError:
+		return e.Result == EResultAccountLoginDeniedNeedTwoFactor
+	}
+
+	if result, ok := target.(EResult); ok {
+		return e.Result == result
+	}
+
+	return false
+}
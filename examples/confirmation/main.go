@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 
 	"github.com/joho/godotenv"
@@ -16,7 +15,7 @@ func main() {
 		log.Fatal(err)
 	}
 
-	client, err := steam.NewClient(new(http.Client), "", "", &steam.Credentials{
+	client, err := steam.NewClient(&steam.Credentials{
 		Username:       os.Getenv("username"),
 		Password:       os.Getenv("password"),
 		SharedSecret:   os.Getenv("sharedSecret"),
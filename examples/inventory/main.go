@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 
 	"github.com/joho/godotenv"
@@ -16,12 +15,12 @@ func main() {
 		log.Fatal(err)
 	}
 
-	client, err := steam.NewClient(new(http.Client), "", steam.LanguageRus, &steam.Credentials{
+	client, err := steam.NewClient(&steam.Credentials{
 		Username:       os.Getenv("username"),
 		Password:       os.Getenv("password"),
 		SharedSecret:   os.Getenv("sharedSecret"),
 		IdentitySecret: os.Getenv("identitySecret"),
-	})
+	}, steam.WithLanguage(steam.LanguageRus))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -5,7 +5,6 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/zergu1ar/steam"
 	"log"
-	"net/http"
 	"os"
 )
 
@@ -15,7 +14,7 @@ func main() {
 		log.Fatal(err)
 	}
 
-	client, err := steam.NewClient(new(http.Client), "", "", &steam.Credentials{
+	client, err := steam.NewClient(&steam.Credentials{
 		Username:       os.Getenv("username"),
 		Password:       os.Getenv("password"),
 		SharedSecret:   os.Getenv("sharedSecret"),
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/zergu1ar/steam"
 	"log"
-	"net/http"
 	"os"
 	"time"
 
@@ -50,7 +49,7 @@ func main() {
 		log.Fatal(err)
 	}
 
-	client, err := steam.NewClient(new(http.Client), "", "", &steam.Credentials{
+	client, err := steam.NewClient(&steam.Credentials{
 		Username:       os.Getenv("username"),
 		Password:       os.Getenv("password"),
 		SharedSecret:   os.Getenv("sharedSecret"),
@@ -5,5 +5,6 @@ go 1.15
 require (
 	github.com/PuerkitoBio/goquery v1.5.1
 	github.com/joho/godotenv v1.3.0
+	golang.org/x/crypto v0.0.0-20201016220609-9e8e0b390897
 	golang.org/x/net v0.0.0-20200904194848-62affa334b73 // indirect
 )
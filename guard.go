@@ -0,0 +1,156 @@
+package steam
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+type GuardCodeKind int
+
+const (
+	GuardCodeEmail GuardCodeKind = iota + 1
+	GuardCodeMobile
+	GuardCodeCaptcha
+)
+
+func (k GuardCodeKind) String() string {
+	switch k {
+	case GuardCodeEmail:
+		return "email"
+	case GuardCodeMobile:
+		return "mobile"
+	case GuardCodeCaptcha:
+		return "captcha"
+	}
+
+	return fmt.Sprintf("GuardCodeKind(%d)", int(k))
+}
+
+// GuardCodeProvider supplies codes steam asks for during login. Hint is the
+// email domain for email codes and the image url for captchas.
+type GuardCodeProvider interface {
+	GuardCode(ctx context.Context, kind GuardCodeKind, hint string) (string, error)
+}
+
+type GuardCodeProviderFunc func(ctx context.Context, kind GuardCodeKind, hint string) (string, error)
+
+func (f GuardCodeProviderFunc) GuardCode(ctx context.Context, kind GuardCodeKind, hint string) (string, error) {
+	return f(ctx, kind, hint)
+}
+
+// ReaderGuardCodeProvider prompts on W and reads one code per line from R.
+// Nothing is read from R until a code is asked for.
+type ReaderGuardCodeProvider struct {
+	mu sync.Mutex
+	r  *bufio.Reader
+	w  io.Writer
+	// pending is the read left running by a call whose context ended, a
+	// blocked read can't be interrupted so the next call waits on it
+	pending chan readResult
+}
+
+type readResult struct {
+	line string
+	err  error
+}
+
+func NewReaderGuardCodeProvider(r io.Reader, w io.Writer) *ReaderGuardCodeProvider {
+	return &ReaderGuardCodeProvider{r: bufio.NewReader(r), w: w}
+}
+
+func NewStdinGuardCodeProvider() *ReaderGuardCodeProvider {
+	return NewReaderGuardCodeProvider(os.Stdin, os.Stderr)
+}
+
+func (p *ReaderGuardCodeProvider) GuardCode(ctx context.Context, kind GuardCodeKind, hint string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	prompt := fmt.Sprintf("Steam %s code", kind)
+	if hint != "" {
+		prompt += " (" + hint + ")"
+	}
+
+	if _, err := fmt.Fprint(p.w, prompt+": "); err != nil {
+		return "", err
+	}
+
+	if p.pending == nil {
+		result := make(chan readResult, 1)
+		go func() {
+			line, err := p.r.ReadString('\n')
+			if err == io.EOF && line != "" {
+				err = nil
+			}
+			result <- readResult{strings.TrimSpace(line), err}
+		}()
+		p.pending = result
+	}
+
+	select {
+	case result := <-p.pending:
+		p.pending = nil
+		return result.line, result.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+type GuardCodeRequest struct {
+	Kind GuardCodeKind
+	Hint string
+	// Code receives the answer, it is buffered so replying never blocks.
+	Code chan<- string
+}
+
+// ChanGuardCodeProvider publishes code requests on Requests for a UI to
+// answer.
+type ChanGuardCodeProvider struct {
+	Requests chan GuardCodeRequest
+}
+
+func NewChanGuardCodeProvider() *ChanGuardCodeProvider {
+	return &ChanGuardCodeProvider{Requests: make(chan GuardCodeRequest)}
+}
+
+func (p *ChanGuardCodeProvider) GuardCode(ctx context.Context, kind GuardCodeKind, hint string) (string, error) {
+	code := make(chan string, 1)
+
+	select {
+	case p.Requests <- GuardCodeRequest{Kind: kind, Hint: hint, Code: code}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	select {
+	case c := <-code:
+		return c, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// guardCode asks the configured provider for a code, err is returned when
+// there is none.
+func (c *Client) guardCode(ctx context.Context, kind GuardCodeKind, hint string, err error) (string, error) {
+	if c.guardCodeProvider == nil {
+		return "", err
+	}
+
+	return c.guardCodeProvider.GuardCode(ctx, kind, hint)
+}
+
+// twoFactorCode generates the mobile code from the shared secret or asks the
+// provider for it.
+func (c *Client) twoFactorCode(ctx context.Context) (string, error) {
+	if len(c.credentials.SharedSecret) != 0 {
+		return GenerateTwoFactorCode(c.credentials.SharedSecret, c.getTimeDiff())
+	}
+
+	return c.guardCode(ctx, GuardCodeMobile, "", RequireTwoFactorError)
+}
@@ -0,0 +1,59 @@
+package steam_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zergu1ar/steam"
+)
+
+type countingReader struct {
+	r     io.Reader
+	reads chan struct{}
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	r.reads <- struct{}{}
+	return r.r.Read(b)
+}
+
+func TestReaderGuardCodeProviderReadsLazily(t *testing.T) {
+	r := &countingReader{r: strings.NewReader("12345\n"), reads: make(chan struct{}, 16)}
+	provider := steam.NewReaderGuardCodeProvider(r, ioutil.Discard)
+
+	select {
+	case <-r.reads:
+		t.Fatal("read before a code was asked for")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	code, err := provider.GuardCode(context.Background(), steam.GuardCodeEmail, "example.com")
+	if err != nil || code != "12345" {
+		t.Fatalf("code %q, %v", code, err)
+	}
+
+	if _, err = provider.GuardCode(context.Background(), steam.GuardCodeEmail, ""); err != io.EOF {
+		t.Fatalf("after the last line: %v", err)
+	}
+}
+
+func TestReaderGuardCodeProviderCanceled(t *testing.T) {
+	pr, pw := io.Pipe()
+	provider := steam.NewReaderGuardCodeProvider(pr, ioutil.Discard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := provider.GuardCode(ctx, steam.GuardCodeMobile, ""); err != context.Canceled {
+		t.Fatalf("canceled: %v", err)
+	}
+
+	go pw.Write([]byte("ABCDE\n"))
+	code, err := provider.GuardCode(context.Background(), steam.GuardCodeMobile, "")
+	if err != nil || code != "ABCDE" {
+		t.Fatalf("code %q, %v", code, err)
+	}
+}
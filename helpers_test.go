@@ -0,0 +1,55 @@
+package steam_test
+
+import (
+	"testing"
+
+	"github.com/zergu1ar/steam"
+	"github.com/zergu1ar/steam/steamtest"
+)
+
+const (
+	testSharedSecret   = "c2hhcmVkc2VjcmV0MTIzNDU2Nzg="
+	testIdentitySecret = "aWRlbnRpdHlzZWNyZXQxMjM0NTY="
+)
+
+func newServer(t *testing.T) *steamtest.Server {
+	srv := steamtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// addBot registers an account with mobile authenticator secrets.
+func addBot(srv *steamtest.Server, username string) steamtest.Account {
+	return srv.AddAccount(steamtest.Account{
+		Username:       username,
+		Password:       "secret",
+		SharedSecret:   testSharedSecret,
+		IdentitySecret: testIdentitySecret,
+	})
+}
+
+func login(t *testing.T, srv *steamtest.Server, username string, opts ...steam.Option) *steam.Client {
+	t.Helper()
+
+	client, err := srv.Client(username, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Destroy)
+
+	if err = client.Login(); err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+// fetchAPIKey loads the web api key used by the IEconService calls.
+func fetchAPIKey(t *testing.T, client *steam.Client) {
+	t.Helper()
+
+	if _, err := client.GetWebAPIKey(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,17 +1,18 @@
 package steam
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
 )
 
 const (
-	inventoryUrl = "http://steamcommunity.com/inventory/%d/%d/%d?"
+	inventoryPath = "/inventory/%d/%d/%d?"
 )
 
 type ItemTag struct {
@@ -51,11 +52,24 @@ type InventoryAppStats struct {
 var inventoryContextRegexp = regexp.MustCompile("var g_rgAppContextData = (.*?);")
 
 func (c *Client) fetchInventory(
+	ctx context.Context,
 	sid SteamID,
 	appID, contextID, startAssetID uint64,
 	filters []Filter,
 	items *[]InventoryItem,
 ) (hasMore bool, lastAssetID uint64, err error) {
+	ctx, span := c.tracer.Start(ctx, "steam.GetInventoryPage",
+		"steamid", sid.ToString(),
+		"appid", appID,
+		"contextid", contextID,
+		"start_assetid", startAssetID,
+	)
+	pageStart := len(*items)
+	defer func() {
+		span.SetAttributes("items", len(*items)-pageStart, "more_items", hasMore)
+		span.End(err)
+	}()
+
 	params := url.Values{
 		"l": {c.language},
 	}
@@ -67,7 +81,13 @@ func (c *Client) fetchInventory(
 		params.Set("count", "250")
 	}
 
-	resp, err := c.client.Get(fmt.Sprintf(inventoryUrl, sid, appID, contextID) + params.Encode())
+	resp, err := c.do(ctx, &request{
+		class:   EndpointInventory,
+		method:  http.MethodGet,
+		url:     c.endpoints.community(fmt.Sprintf(inventoryPath, sid, appID, contextID)) + params.Encode(),
+		referer: c.endpoints.community("/profiles/" + sid.ToString() + "/inventory"),
+		ajax:    true,
+	})
 	if resp != nil {
 		defer resp.Body.Close()
 	}
@@ -76,6 +96,11 @@ func (c *Client) fetchInventory(
 		return false, 0, err
 	}
 
+	// steam answers a throttled or private inventory with a null body
+	if resp.StatusCode != http.StatusOK {
+		return false, 0, newSteamError(resp, "")
+	}
+
 	type Asset struct {
 		AppID      uint32 `json:"appid"`
 		ContextID  uint64 `json:"contextid,string"`
@@ -102,7 +127,7 @@ func (c *Client) fetchInventory(
 
 	if response.Success == 0 {
 		if len(response.ErrorMsg) != 0 {
-			return false, 0, errors.New(response.ErrorMsg)
+			return false, 0, newSteamError(resp, response.ErrorMsg)
 		}
 
 		return false, 0, nil // empty inventory
@@ -166,26 +191,44 @@ func (c *Client) fetchInventory(
 }
 
 func (c *Client) GetInventory(sid SteamID, appID, contextID uint64, tradableOnly bool) ([]InventoryItem, error) {
+	return c.GetInventoryContext(context.Background(), sid, appID, contextID, tradableOnly)
+}
+
+func (c *Client) GetInventoryContext(ctx context.Context, sid SteamID, appID, contextID uint64, tradableOnly bool) ([]InventoryItem, error) {
 	filters := []Filter{}
 
 	if tradableOnly {
 		filters = append(filters, IsTradable(tradableOnly))
 	}
 
-	return c.GetFilterableInventory(sid, appID, contextID, filters)
+	return c.GetFilterableInventoryContext(ctx, sid, appID, contextID, filters)
 }
 
 func (c *Client) GetFilterableInventory(sid SteamID, appID, contextID uint64, filters []Filter) ([]InventoryItem, error) {
+	return c.GetFilterableInventoryContext(context.Background(), sid, appID, contextID, filters)
+}
+
+func (c *Client) GetFilterableInventoryContext(ctx context.Context, sid SteamID, appID, contextID uint64, filters []Filter) (_ []InventoryItem, err error) {
+	ctx, span := c.tracer.Start(ctx, "steam.GetInventory",
+		"steamid", sid.ToString(),
+		"appid", appID,
+		"contextid", contextID,
+	)
+	defer func() {
+		span.End(err)
+	}()
+
 	items := []InventoryItem{}
 	startAssetID := uint64(0)
 
-	for {
-		hasMore, lastAssetID, err := c.fetchInventory(sid, appID, contextID, startAssetID, filters, &items)
+	for pages := 1; ; pages++ {
+		hasMore, lastAssetID, err := c.fetchInventory(ctx, sid, appID, contextID, startAssetID, filters, &items)
 		if err != nil {
 			return nil, err
 		}
 
 		if !hasMore {
+			span.SetAttributes("pages", pages, "items", len(items))
 			break
 		}
 
@@ -196,7 +239,14 @@ func (c *Client) GetFilterableInventory(sid SteamID, appID, contextID uint64, fi
 }
 
 func (c *Client) GetInventoryAppStats(sid SteamID) (map[string]InventoryAppStats, error) {
-	resp, err := c.client.Get("https://steamcommunity.com/profiles/" + sid.ToString() + "/inventory")
+	return c.GetInventoryAppStatsContext(context.Background(), sid)
+}
+
+func (c *Client) GetInventoryAppStatsContext(ctx context.Context, sid SteamID) (map[string]InventoryAppStats, error) {
+	resp, err := c.do(ctx, &request{
+		method: http.MethodGet,
+		url:    c.endpoints.community("/profiles/" + sid.ToString() + "/inventory"),
+	})
 	if resp != nil {
 		defer resp.Body.Close()
 	}
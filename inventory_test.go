@@ -0,0 +1,23 @@
+package steam_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zergu1ar/steam"
+	"github.com/zergu1ar/steam/steamtest"
+)
+
+func TestGetInventoryRateLimited(t *testing.T) {
+	srv := newServer(t)
+	bot := addBot(srv, "bot")
+	srv.AddItems(bot.SteamID, 730, 2, steamtest.Item{ClassID: 1})
+	client := login(t, srv, "bot")
+
+	// steam's answer to a throttled inventory request
+	srv.Fail("/inventory/", steamtest.Failure{Status: 429, Body: "null", Times: -1})
+	items, err := client.GetInventory(bot.SteamID, 730, 2, false)
+	if !errors.Is(err, steam.EResultRateLimitExceeded) {
+		t.Fatalf("got %d items, %v, want a rate limit error", len(items), err)
+	}
+}
@@ -0,0 +1,159 @@
+package steam
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// sessionJar is the jar installed on the client's http.Client. Login swaps
+// the jar behind it, so requests in flight and other copies of the
+// http.Client (e.g. with a different transport) keep sharing the account's
+// cookies.
+//
+// http.CookieJar only hands back names and values, so the jar also keeps the
+// attributes of every cookie set through it for ExportSession.
+type sessionJar struct {
+	mu      sync.RWMutex
+	jar     http.CookieJar
+	cookies map[string]savedCookie
+}
+
+type savedCookie struct {
+	url    string
+	cookie sessionCookie
+}
+
+type jarState struct {
+	jar     http.CookieJar
+	cookies map[string]savedCookie
+}
+
+func (j *sessionJar) current() http.CookieJar {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.jar
+}
+
+// reset replaces the cookies with an empty jar.
+func (j *sessionJar) reset(jar http.CookieJar) {
+	j.setState(jarState{jar: jar})
+}
+
+func (j *sessionJar) state() jarState {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return jarState{jar: j.jar, cookies: j.cookies}
+}
+
+func (j *sessionJar) setState(state jarState) {
+	j.mu.Lock()
+	j.jar = state.jar
+	j.cookies = state.cookies
+	j.mu.Unlock()
+}
+
+func (j *sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.jar.SetCookies(u, cookies)
+
+	if j.cookies == nil {
+		j.cookies = make(map[string]savedCookie)
+	}
+
+	// the path matters for cookies without a Path attribute
+	setFor := u.Scheme + "://" + u.Host + u.EscapedPath()
+	now := time.Now()
+	for _, cookie := range cookies {
+		saved := sessionCookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   cookie.Domain,
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+		}
+
+		switch {
+		case cookie.MaxAge > 0:
+			saved.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second).Unix()
+		case !cookie.Expires.IsZero():
+			saved.Expires = cookie.Expires.Unix()
+		}
+
+		// host-only cookies are told apart by the url they were set for
+		key := cookie.Domain + ";" + cookie.Path + ";" + cookie.Name
+		if cookie.Domain == "" {
+			key = setFor + ";" + key
+		}
+
+		if cookie.MaxAge < 0 || (saved.Expires != 0 && saved.Expires <= now.Unix()) {
+			delete(j.cookies, key)
+			continue
+		}
+		j.cookies[key] = savedCookie{url: setFor, cookie: saved}
+	}
+}
+
+func (j *sessionJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.current().Cookies(u)
+}
+
+// saved returns the unexpired cookies set through the jar, by the url they
+// were set for.
+func (j *sessionJar) saved() map[string][]sessionCookie {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	now := time.Now().Unix()
+	saved := make(map[string][]sessionCookie)
+	for _, cookie := range j.cookies {
+		if cookie.cookie.Expires != 0 && cookie.cookie.Expires <= now {
+			continue
+		}
+		saved[cookie.url] = append(saved[cookie.url], cookie.cookie)
+	}
+
+	return saved
+}
+
+// loadJar builds a jar from cookies returned by saved.
+func loadJar(cookies map[string][]sessionCookie) (jarState, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return jarState{}, err
+	}
+
+	loaded := &sessionJar{jar: jar}
+	for setFor, cookies := range cookies {
+		u, err := url.Parse(setFor)
+		if err != nil {
+			return jarState{}, err
+		}
+
+		httpCookies := make([]*http.Cookie, 0, len(cookies))
+		for _, cookie := range cookies {
+			httpCookie := &http.Cookie{
+				Name:     cookie.Name,
+				Value:    cookie.Value,
+				Domain:   cookie.Domain,
+				Path:     cookie.Path,
+				Secure:   cookie.Secure,
+				HttpOnly: cookie.HttpOnly,
+			}
+			if cookie.Expires != 0 {
+				httpCookie.Expires = time.Unix(cookie.Expires, 0)
+			}
+			httpCookies = append(httpCookies, httpCookie)
+		}
+		loaded.SetCookies(u, httpCookies)
+	}
+
+	return loaded.state(), nil
+}
@@ -0,0 +1,42 @@
+package steam
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSessionJarKeepsCookieAttributes(t *testing.T) {
+	inner, _ := cookiejar.New(nil)
+	jar := &sessionJar{jar: inner}
+
+	login, _ := url.Parse("https://login.example.com/jwt/finalizelogin")
+	jar.SetCookies(login, []*http.Cookie{
+		{Name: "shared", Value: "1", Domain: "example.com", Path: "/", Secure: true},
+		{Name: "hostonly", Value: "2", Path: "/"},
+		{Name: "expired", Value: "3", Path: "/", Expires: time.Now().Add(-time.Hour)},
+	})
+
+	state, err := loadJar(jar.saved())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	community, _ := url.Parse("https://community.example.com/")
+	if cookies := state.jar.Cookies(community); len(cookies) != 1 || cookies[0].Name != "shared" {
+		t.Fatalf("community cookies %v", cookies)
+	}
+
+	// secure cookies are not sent over plain http
+	plain, _ := url.Parse("http://community.example.com/")
+	if cookies := state.jar.Cookies(plain); len(cookies) != 0 {
+		t.Fatalf("plain http cookies %v", cookies)
+	}
+
+	login.Path = "/"
+	if cookies := state.jar.Cookies(login); len(cookies) != 2 {
+		t.Fatalf("login cookies %v", cookies)
+	}
+}
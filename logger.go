@@ -0,0 +1,57 @@
+package steam
+
+import "net/url"
+
+// Logger is implemented by *slog.Logger, args are alternating key-value pairs.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+
+const redacted = "[REDACTED]"
+
+// sensitiveParams are query parameters never written to the log: api keys,
+// confirmation keys, tokens and session ids.
+var sensitiveParams = []string{
+	"key", "k", "ck", "access_token", "refresh_token", "token",
+	"sessionid", "password", "encrypted_password", "twofactorcode", "emailauth",
+}
+
+// redactURL masks sensitive query parameters so the url can be logged.
+func redactURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return redacted
+	}
+
+	u.User = nil
+	if u.RawQuery != "" {
+		query := u.Query()
+		for _, param := range sensitiveParams {
+			if _, ok := query[param]; ok {
+				query.Set(param, redacted)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String()
+}
+
+// redactError strips secrets from the url reported by net/http errors.
+func redactError(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return &url.Error{Op: urlErr.Op, URL: redactURL(urlErr.URL), Err: urlErr.Err}
+	}
+
+	return err
+}
@@ -0,0 +1,172 @@
+package steam_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zergu1ar/steam"
+	"github.com/zergu1ar/steam/steamtest"
+)
+
+// recordLogger keeps every record in the form a text handler would print
+// its values.
+type recordLogger struct {
+	mu      sync.Mutex
+	records []string
+	values  []interface{}
+}
+
+func (l *recordLogger) record(msg string, args []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records = append(l.records, fmt.Sprintln(append([]interface{}{msg}, args...)...))
+	for i := 1; i < len(args); i += 2 {
+		l.values = append(l.values, args[i])
+	}
+}
+
+func (l *recordLogger) Debug(msg string, args ...interface{}) { l.record(msg, args) }
+func (l *recordLogger) Info(msg string, args ...interface{})  { l.record(msg, args) }
+func (l *recordLogger) Warn(msg string, args ...interface{})  { l.record(msg, args) }
+func (l *recordLogger) Error(msg string, args ...interface{}) { l.record(msg, args) }
+
+func TestLogsRedactSecrets(t *testing.T) {
+	srv := newServer(t)
+	bot := srv.AddAccount(steamtest.Account{
+		Username:       "bot",
+		Password:       "correct-horse-battery-staple",
+		SharedSecret:   testSharedSecret,
+		IdentitySecret: testIdentitySecret,
+	})
+	confirmation := srv.AddConfirmation(steamtest.Confirmation{Owner: bot.SteamID, Creator: 1})
+
+	logger := &recordLogger{}
+	client := login(t, srv, "bot", steam.WithLogger(logger))
+	key, err := client.GetWebAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.GetTradeOffers(steam.TradeFilterSentOffers, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the transport error of a dropped request carries the full url
+	srv.Fail("/mobileconf/conf", steamtest.Failure{Drop: true, Times: 2})
+	confirmations, err := client.GetConfirmations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(confirmations) != 1 || confirmations[0].ID != confirmation.ID {
+		t.Fatalf("confirmations %+v, want %d", confirmations, confirmation.ID)
+	}
+
+	if err = client.AnswerConfirmation(confirmations[0], steam.AnswerAllow); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := client.ExportSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var session struct {
+		ID      string `json:"sessionid"`
+		Refresh string `json:"refresh_token"`
+		Cookies map[string][]struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"cookies"`
+	}
+	if err = json.Unmarshal(data, &session); err != nil {
+		t.Fatal(err)
+	}
+
+	secrets := map[string]string{
+		"password":        bot.Password,
+		"shared secret":   bot.SharedSecret,
+		"identity secret": bot.IdentitySecret,
+		"api key":         key,
+		"access token":    client.AccessToken(),
+		"refresh token":   session.Refresh,
+		"session id":      session.ID,
+	}
+	for _, cookies := range session.Cookies {
+		for _, cookie := range cookies {
+			if cookie.Name == "sessionid" || strings.HasPrefix(cookie.Name, "steamLogin") || strings.HasPrefix(cookie.Name, "steamRefresh") {
+				secrets[cookie.Name+" cookie"] = cookie.Value
+			}
+		}
+	}
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	if len(logger.records) == 0 {
+		t.Fatal("nothing was logged")
+	}
+
+	for name, secret := range secrets {
+		if secret == "" {
+			t.Fatalf("%s is empty", name)
+		}
+
+		for _, record := range logger.records {
+			if strings.Contains(record, secret) {
+				t.Errorf("%s logged: %s", name, record)
+			}
+		}
+	}
+
+	// confirmation keys change every second, every logged url is checked
+	// instead
+	urls := 0
+	for _, value := range logger.values {
+		rawurl := fmt.Sprint(value)
+		if i := strings.Index(rawurl, "http"); i >= 0 {
+			rawurl = strings.Fields(rawurl[i:])[0]
+		}
+
+		u, err := url.Parse(strings.Trim(rawurl, `"`))
+		if err != nil || u.RawQuery == "" {
+			continue
+		}
+
+		urls++
+		for _, param := range []string{"k", "ck", "key", "access_token", "sessionid"} {
+			if v, ok := u.Query()[param]; ok && v[0] != "[REDACTED]" {
+				t.Errorf("%s=%s logged: %s", param, v[0], value)
+			}
+		}
+	}
+
+	if urls == 0 {
+		t.Fatal("no url with a query was logged")
+	}
+}
+
+func TestCredentialsString(t *testing.T) {
+	credentials := steam.Credentials{
+		Username:       "bot",
+		Password:       "correct-horse-battery-staple",
+		SharedSecret:   testSharedSecret,
+		IdentitySecret: testIdentitySecret,
+	}
+
+	for _, s := range []string{fmt.Sprint(credentials), fmt.Sprintf("%+v", &credentials)} {
+		if !strings.Contains(s, "bot") {
+			t.Errorf("%s: username missing", s)
+		}
+
+		for _, secret := range []string{credentials.Password, credentials.SharedSecret, credentials.IdentitySecret} {
+			if strings.Contains(s, secret) {
+				t.Errorf("%s: contains %s", s, secret)
+			}
+		}
+	}
+}
@@ -1,10 +1,12 @@
 package steam
 
 import (
-	"crypto/md5"
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha1"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,7 +15,6 @@ import (
 	"net/http/cookiejar"
 	"net/url"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -26,12 +27,17 @@ type LoginResponse struct {
 }
 
 type LoginSession struct {
-	Success           bool   `json:"success"`
-	LoginComplete     bool   `json:"login_complete"`
-	RequiresTwoFactor bool   `json:"requires_twofactor"`
-	Message           string `json:"message"`
-	RedirectURI       string `json:"redirect_uri"`
-	OAuth             OAuth  `json:"transfer_parameters"`
+	Success           bool        `json:"success"`
+	LoginComplete     bool        `json:"login_complete"`
+	RequiresTwoFactor bool        `json:"requires_twofactor"`
+	EmailAuthNeeded   bool        `json:"emailauth_needed"`
+	EmailDomain       string      `json:"emaildomain"`
+	EmailSteamID      string      `json:"emailsteamid"`
+	CaptchaNeeded     bool        `json:"captcha_needed"`
+	CaptchaGID        json.Number `json:"captcha_gid"`
+	Message           string      `json:"message"`
+	RedirectURI       string      `json:"redirect_uri"`
+	OAuth             OAuth       `json:"transfer_parameters"`
 }
 
 type OAuth struct {
@@ -41,45 +47,191 @@ type OAuth struct {
 	Auth        string  `json:"auth"`
 	TokenSecure string  `json:"token_secure"`
 	WebCookie   string  `json:"webcookie"`
+
+	AccessToken  string `json:"-"`
+	RefreshToken string `json:"-"`
 }
 
 func (c *Client) Login() error {
-	err := c.setupCookie()
+	return c.LoginContext(context.Background())
+}
+
+func (c *Client) LoginContext(ctx context.Context) (err error) {
+	ctx, span := c.tracer.Start(ctx, "steam.Login", "account", c.credentials.Username)
+	defer func() {
+		span.End(err)
+	}()
+
+	if c.restoreStoredSession(ctx) {
+		span.SetAttributes("restored", true)
+		return nil
+	}
+
+	return c.login(ctx)
+}
+
+func (c *Client) login(ctx context.Context) error {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+
+	c.logger.Info("logging in to steam", "account", c.credentials.Username)
+	err := c.authLogin(ctx)
+	c.metrics.ObserveLogin(LoginMethodPassword, err)
 	if err != nil {
+		c.logger.Warn("steam login failed", "account", c.credentials.Username, "error", err)
 		return err
 	}
 
-	response, err := c.makeLoginRequest(c.credentials.Username)
-	if err != nil {
+	c.logger.Info("logged in to steam", "account", c.credentials.Username, "steamid", c.GetSteamId())
+	c.saveSession()
+	return nil
+}
+
+// LegacyLogin logs in through the deprecated /login/dologin endpoint.
+func (c *Client) LegacyLogin() error {
+	return c.LegacyLoginContext(context.Background())
+}
+
+func (c *Client) LegacyLoginContext(ctx context.Context) error {
+	return c.legacyLogin(ctx, &legacyGuard{})
+}
+
+// LegacyLoginWithCaptcha retries LegacyLogin with the text of the captcha
+// from a CaptchaRequiredError.
+func (c *Client) LegacyLoginWithCaptcha(gid, text string) error {
+	return c.LegacyLoginWithCaptchaContext(context.Background(), gid, text)
+}
+
+func (c *Client) LegacyLoginWithCaptchaContext(ctx context.Context, gid, text string) error {
+	return c.legacyLogin(ctx, &legacyGuard{CaptchaGID: gid, CaptchaText: text})
+}
+
+func (c *Client) legacyLogin(ctx context.Context, guard *legacyGuard) (err error) {
+	ctx, span := c.tracer.Start(ctx, "steam.LegacyLogin", "account", c.credentials.Username)
+
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+
+	defer func() {
+		c.metrics.ObserveLogin(LoginMethodLegacy, err)
+		span.End(err)
+	}()
+
+	if c.credentials.Password == "" {
+		return PasswordEmptyError
+	}
+
+	if err = c.setupCookie(ctx); err != nil {
 		return err
 	}
 
-	var twoFactorCode string
 	if len(c.credentials.SharedSecret) != 0 {
-		if twoFactorCode, err = GenerateTwoFactorCode(c.credentials.SharedSecret, c.getTimeDiff()); err != nil {
+		if guard.TwoFactorCode, err = c.twoFactorCode(ctx); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		response, err := c.makeLoginRequest(ctx, c.credentials.Username)
+		if err != nil {
+			return err
+		}
+
+		loginSession, err := c.proceedDirectLogin(ctx, response, c.credentials.Username, c.credentials.Password, guard)
+		if err != nil {
+			return err
+		}
+
+		if loginSession.Success {
+			c.logger.Info("logged in to steam", "account", c.credentials.Username, "steamid", c.GetSteamId(), "legacy", true)
+			break
+		}
+
+		if attempt == legacyLoginAttempts-1 {
+			return c.legacyLoginError(loginSession)
+		}
+
+		if err = c.answerLegacyGuard(ctx, loginSession, guard); err != nil {
 			return err
 		}
 	}
 
-	return c.proceedDirectLogin(response, c.credentials.Username, c.credentials.Password, twoFactorCode)
+	c.saveSession()
+	return nil
 }
 
-func (c *Client) setupCookie() error {
-	// clear storage
-	if jar, err := cookiejar.New(nil); err == nil {
-		c.client.Jar = jar
+// legacyLoginAttempts is the number of dologin requests sent per login.
+const legacyLoginAttempts = 3
+
+// legacyGuard holds the steam guard answers sent to dologin.
+type legacyGuard struct {
+	TwoFactorCode string
+	EmailAuth     string
+	EmailSteamID  string
+	CaptchaGID    string
+	CaptchaText   string
+}
+
+func (g *legacyGuard) captchaGID() string {
+	if g.CaptchaGID == "" {
+		return "-1"
 	}
+	return g.CaptchaGID
+}
 
-	req, err := http.NewRequest(
-		http.MethodGet,
-		loginUrl,
-		nil,
-	)
-	if err != nil {
-		return err
+// answerLegacyGuard fills guard with what a failed dologin asked for.
+func (c *Client) answerLegacyGuard(ctx context.Context, loginSession *LoginSession, guard *legacyGuard) error {
+	var err error
+
+	switch {
+	case loginSession.CaptchaNeeded:
+		captcha := c.captchaError(loginSession)
+		guard.CaptchaGID = captcha.GID
+		guard.CaptchaText, err = c.guardCode(ctx, GuardCodeCaptcha, captcha.URL, captcha)
+	case loginSession.RequiresTwoFactor:
+		guard.TwoFactorCode, err = c.twoFactorCode(ctx)
+	case loginSession.EmailAuthNeeded:
+		guard.EmailSteamID = loginSession.EmailSteamID
+		guard.EmailAuth, err = c.guardCode(ctx, GuardCodeEmail, loginSession.EmailDomain, RequireEmailCodeError)
+	default:
+		err = errors.New(loginSession.Message)
+	}
+
+	return err
+}
+
+// legacyLoginError classifies a failed dologin once no attempts are left.
+func (c *Client) legacyLoginError(loginSession *LoginSession) error {
+	switch {
+	case loginSession.CaptchaNeeded:
+		return c.captchaError(loginSession)
+	case loginSession.RequiresTwoFactor:
+		return RequireTwoFactorError
+	case loginSession.EmailAuthNeeded:
+		return RequireEmailCodeError
 	}
 
-	resp, err := c.client.Do(req)
+	return errors.New(loginSession.Message)
+}
+
+func (c *Client) captchaError(loginSession *LoginSession) *CaptchaRequiredError {
+	return &CaptchaRequiredError{
+		GID: loginSession.CaptchaGID.String(),
+		URL: c.endpoints.community(captchaPath) + url.QueryEscape(loginSession.CaptchaGID.String()),
+	}
+}
+
+func (c *Client) setupCookie(ctx context.Context) error {
+	// clear storage
+	if jar, err := cookiejar.New(nil); err == nil {
+		c.jar.reset(jar)
+	}
+
+	resp, err := c.do(ctx, &request{
+		class:  EndpointLogin,
+		method: http.MethodGet,
+		url:    c.endpoints.community(loginPath),
+	})
 	if err != nil {
 		return err
 	}
@@ -91,7 +243,7 @@ func (c *Client) setupCookie() error {
 		return err
 	}
 
-	steamUrl, err := url.Parse(baseUrl)
+	steamUrl, err := url.Parse(c.endpoints.Community)
 	if err != nil {
 		return err
 	}
@@ -107,36 +259,24 @@ func (c *Client) setupCookie() error {
 		cookies = append(cookies, &http.Cookie{Name: cookie.Name, Value: cookie.Value})
 	}
 
-	jar.SetCookies(steamUrl, cookies)
-	c.client.Jar = jar
+	c.jar.reset(jar)
+	c.jar.SetCookies(steamUrl, cookies)
 
 	return nil
 }
 
-func (c *Client) makeLoginRequest(accountName string) (*LoginResponse, error) {
-	reqData := url.Values{
-		"username":   {accountName},
-		"donotcache": {strconv.FormatInt(time.Now().Unix()*1000, 10)},
-	}.Encode()
-
-	req, err := http.NewRequest(
-		http.MethodPost,
-		rsaUrl,
-		strings.NewReader(reqData),
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
-	req.Header.Add("Content-Length", strconv.Itoa(len(reqData)))
-	req.Header.Add("X-Requested-With", "XMLHttpRequest")
-	req.Header.Add("Origin", baseUrl)
-	req.Header.Add("Referer", loginUrl)
-	req.Header.Add("User-Agent", c.useragent)
-	req.Header.Add("Accept", "*/*")
-
-	resp, err := c.client.Do(req)
+func (c *Client) makeLoginRequest(ctx context.Context, accountName string) (*LoginResponse, error) {
+	resp, err := c.do(ctx, &request{
+		class:  EndpointLogin,
+		method: http.MethodPost,
+		url:    c.endpoints.community(rsaPath),
+		form: url.Values{
+			"username":   {accountName},
+			"donotcache": {strconv.FormatInt(time.Now().Unix()*1000, 10)},
+		},
+		referer: c.endpoints.community(loginPath),
+		ajax:    true,
+	})
 	if resp != nil {
 		defer resp.Body.Close()
 	}
@@ -157,74 +297,49 @@ func (c *Client) makeLoginRequest(accountName string) (*LoginResponse, error) {
 	return &response, nil
 }
 
-func (c *Client) proceedDirectLogin(response *LoginResponse, accountName, password, twoFactorCode string) error {
-	var n big.Int
-	n.SetString(response.PublicKeyMod, 16)
-
-	exp, err := strconv.ParseInt(response.PublicKeyExp, 16, 32)
-	if err != nil {
-		return err
-	}
-
-	pub := rsa.PublicKey{N: &n, E: int(exp)}
-	rsaOut, err := rsa.EncryptPKCS1v15(rand.Reader, &pub, []byte(password))
-	if err != nil {
-		return err
-	}
-
-	reqData := url.Values{
-		"captcha_text":      {""},
-		"captchagid":        {"-1"},
-		"emailauth":         {""},
-		"emailsteamid":      {""},
-		"username":          {accountName},
-		"password":          {base64.StdEncoding.EncodeToString(rsaOut)},
-		"remember_login":    {"true"},
-		"rsatimestamp":      {response.Timestamp},
-		"twofactorcode":     {twoFactorCode},
-		"donotcache":        {strconv.FormatInt(time.Now().Unix()*1000, 10)},
-		"loginfriendlyname": {""},
-	}.Encode()
-
-	req, err := http.NewRequest(
-		http.MethodPost,
-		doLoginUrl,
-		strings.NewReader(reqData),
-	)
+func (c *Client) proceedDirectLogin(ctx context.Context, response *LoginResponse, accountName, password string, guard *legacyGuard) (*LoginSession, error) {
+	encryptedPassword, err := encryptPassword(password, response.PublicKeyMod, response.PublicKeyExp)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
-	req.Header.Add("Content-Length", strconv.Itoa(len(reqData)))
-	req.Header.Add("X-Requested-With", "XMLHttpRequest")
-	req.Header.Add("Origin", baseUrl)
-	req.Header.Add("Referer", loginUrl)
-	req.Header.Add("User-Agent", c.useragent)
-	req.Header.Add("Accept", "*/*")
-
-	resp, err := c.client.Do(req)
+	resp, err := c.do(ctx, &request{
+		class:  EndpointLogin,
+		method: http.MethodPost,
+		url:    c.endpoints.community(doLoginPath),
+		form: url.Values{
+			"captcha_text":      {guard.CaptchaText},
+			"captchagid":        {guard.captchaGID()},
+			"emailauth":         {guard.EmailAuth},
+			"emailsteamid":      {guard.EmailSteamID},
+			"username":          {accountName},
+			"password":          {encryptedPassword},
+			"remember_login":    {"true"},
+			"rsatimestamp":      {response.Timestamp},
+			"twofactorcode":     {guard.TwoFactorCode},
+			"donotcache":        {strconv.FormatInt(time.Now().Unix()*1000, 10)},
+			"loginfriendlyname": {""},
+		},
+		referer: c.endpoints.community(loginPath),
+		ajax:    true,
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	defer resp.Body.Close()
 
 	loginSession := &LoginSession{}
 	if err := json.NewDecoder(resp.Body).Decode(&loginSession); err != nil {
-		return err
+		return nil, err
 	}
 
 	if !loginSession.Success {
-		if loginSession.RequiresTwoFactor {
-			return RequireTwoFactorError
-		}
-
-		return errors.New(loginSession.Message)
+		return loginSession, nil
 	}
 
-	steamUrl, _ := url.Parse(baseUrl)
-	cookies := c.client.Jar.Cookies(steamUrl)
+	steamUrl, _ := url.Parse(c.endpoints.Community)
+	cookies := c.jar.Cookies(steamUrl)
 	for _, cookie := range cookies {
 		if cookie.Name == "sessionid" {
 			loginSession.OAuth.ID = cookie.Value
@@ -232,17 +347,43 @@ func (c *Client) proceedDirectLogin(response *LoginResponse, accountName, passwo
 		}
 	}
 
-	c.session = &loginSession.OAuth
+	if loginSession.OAuth.ID == "" {
+		return nil, InvalidSessionError
+	}
+
+	loginSession.OAuth.DeviceID = c.deviceID(loginSession.OAuth.SteamID)
+
+	c.setSession(&loginSession.OAuth)
+
+	return loginSession, nil
+}
+
+// deviceID returns Credentials.DeviceID, or the id steam-totp derives from
+// the steam id, which is what most authenticators register.
+func (c *Client) deviceID(steamID SteamID) string {
+	if c.credentials.DeviceID != "" {
+		return c.credentials.DeviceID
+	}
+
+	sum := sha1.Sum([]byte(steamID.ToString()))
+	hash := hex.EncodeToString(sum[:])
+	return "android:" + hash[:8] + "-" + hash[8:12] + "-" + hash[12:16] + "-" + hash[16:20] + "-" + hash[20:32]
+}
+
+func encryptPassword(password, modulus, exponent string) (string, error) {
+	var n big.Int
+	n.SetString(modulus, 16)
 
-	if c.session.ID == "" {
-		return InvalidSessionError
+	exp, err := strconv.ParseInt(exponent, 16, 32)
+	if err != nil {
+		return "", err
 	}
 
-	sum := md5.Sum([]byte(accountName + password))
-	c.session.DeviceID = fmt.Sprintf(
-		"android:%x-%x-%x-%x-%x",
-		sum[:2], sum[2:4], sum[4:6], sum[6:8], sum[8:10],
-	)
+	pub := rsa.PublicKey{N: &n, E: int(exp)}
+	rsaOut, err := rsa.EncryptPKCS1v15(rand.Reader, &pub, []byte(password))
+	if err != nil {
+		return "", err
+	}
 
-	return nil
+	return base64.StdEncoding.EncodeToString(rsaOut), nil
 }
@@ -0,0 +1,71 @@
+package steam_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zergu1ar/steam"
+	"github.com/zergu1ar/steam/steamtest"
+)
+
+func TestLegacyLoginCaptchaAfterLastAttempt(t *testing.T) {
+	srv := newServer(t)
+	srv.AddAccount(steamtest.Account{Username: "bot", Password: "secret", Captcha: "h3ll0"})
+
+	wrong := steam.GuardCodeProviderFunc(func(ctx context.Context, kind steam.GuardCodeKind, hint string) (string, error) {
+		return "wrong", nil
+	})
+
+	client, err := srv.Client("bot", steam.WithGuardCodeProvider(wrong))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Destroy()
+
+	err = client.LegacyLogin()
+	var captcha *steam.CaptchaRequiredError
+	if !errors.As(err, &captcha) {
+		t.Fatalf("expected a captcha error, got %v", err)
+	}
+	if hits := srv.Hits("/login/dologin"); hits != 3 {
+		t.Fatalf("%d dologin requests, want 3", hits)
+	}
+
+	if err = client.LegacyLoginWithCaptcha(captcha.GID, "h3ll0"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	srv := newServer(t)
+	bot := addBot(srv, "bot")
+
+	client := login(t, srv, "bot")
+	if client.GetSteamId() != bot.SteamID {
+		t.Fatalf("logged in as %d, want %d", client.GetSteamId(), bot.SteamID)
+	}
+
+	if alive, err := client.IsSessionAlive(); err != nil || !alive {
+		t.Fatal("session is not alive", err)
+	}
+}
+
+func TestLoginWrongPassword(t *testing.T) {
+	srv := newServer(t)
+	addBot(srv, "bot")
+
+	client, err := steam.NewClient(
+		&steam.Credentials{Username: "bot", Password: "wrong"},
+		steam.WithEndpoints(srv.Endpoints()),
+		steam.WithHTTPClient(srv.Server.Client()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Destroy()
+
+	if err = client.Login(); !errors.Is(err, steam.InvalidCredentialsError) {
+		t.Fatalf("got %v, want %v", err, steam.InvalidCredentialsError)
+	}
+}
@@ -0,0 +1,346 @@
+package steam
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPollInterval = 30 * time.Second
+	// pollCutoffSlack widens the historical cutoff so offers updated while
+	// the previous poll was in flight are not missed.
+	pollCutoffSlack = time.Minute
+)
+
+type OfferEventType int
+
+const (
+	// OfferNew is a received offer seen for the first time in the active state.
+	OfferNew OfferEventType = iota + 1
+	OfferSentChanged
+	OfferReceivedChanged
+	// OfferAccepted, OfferExpired and OfferInEscrow follow the changed
+	// event of the offer that reached the state.
+	OfferAccepted
+	OfferExpired
+	OfferInEscrow
+)
+
+var offerEventNames = map[OfferEventType]string{
+	OfferNew:             "NewOffer",
+	OfferSentChanged:     "SentOfferChanged",
+	OfferReceivedChanged: "ReceivedOfferChanged",
+	OfferAccepted:        "OfferAccepted",
+	OfferExpired:         "OfferExpired",
+	OfferInEscrow:        "OfferInEscrow",
+}
+
+func (t OfferEventType) String() string {
+	if name, ok := offerEventNames[t]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+type OfferEvent struct {
+	Type  OfferEventType
+	Offer *TradeOffer
+	// OldState is the state the offer had in the previous poll, zero for
+	// new offers.
+	OldState uint8
+	// Meta is set for sent offers registered with TrackOffer.
+	Meta OfferMeta
+}
+
+type ManagerOption func(*TradeOfferManager)
+
+// WithPollInterval sets how often Run polls, Run fails with
+// InvalidPollIntervalError unless interval is positive.
+func WithPollInterval(interval time.Duration) ManagerOption {
+	return func(m *TradeOfferManager) {
+		m.interval = interval
+	}
+}
+
+func WithPollData(data PollData) ManagerOption {
+	return func(m *TradeOfferManager) {
+		m.data = data.clone()
+	}
+}
+
+// WithPollDataStore loads the poll data from store before the first poll
+// and saves it after every poll, before any event is fired.
+func WithPollDataStore(store PollDataStore) ManagerOption {
+	return func(m *TradeOfferManager) {
+		m.store = store
+	}
+}
+
+func WithManagerLogger(logger Logger) ManagerOption {
+	return func(m *TradeOfferManager) {
+		m.logger = logger
+	}
+}
+
+// TradeOfferManager polls GetTradeOffers and reports offer state changes to
+// a handler. Handlers run on the polling goroutine after the poll data was
+// saved, they may call TrackOffer.
+type TradeOfferManager struct {
+	service  TradeOfferService
+	handler  func(OfferEvent)
+	interval time.Duration
+	store    PollDataStore
+	logger   Logger
+	now      func() time.Time
+
+	pollMu sync.Mutex
+	loaded bool
+	mu     sync.Mutex
+	data   PollData
+}
+
+func NewTradeOfferManager(service TradeOfferService, handler func(OfferEvent), opts ...ManagerOption) *TradeOfferManager {
+	m := &TradeOfferManager{
+		service:  service,
+		handler:  handler,
+		interval: defaultPollInterval,
+		logger:   nopLogger{},
+		now:      time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	m.data = m.data.clone()
+
+	return m
+}
+
+// load reads the stored poll data once, the caller holds pollMu.
+func (m *TradeOfferManager) load() error {
+	if m.loaded || m.store == nil {
+		return nil
+	}
+
+	data, err := m.store.Load()
+	if err == PollDataNotFoundError {
+		m.loaded = true
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.data = data.clone()
+	m.mu.Unlock()
+	m.loaded = true
+
+	return nil
+}
+
+// save persists data and makes it current, the caller holds pollMu.
+func (m *TradeOfferManager) save(data PollData) error {
+	if m.store != nil {
+		if err := m.store.Save(data); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	m.data = data
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Run polls until ctx is done. Failed polls are logged and retried on the
+// next tick.
+func (m *TradeOfferManager) Run(ctx context.Context) error {
+	if m.interval <= 0 {
+		return InvalidPollIntervalError
+	}
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			m.logger.Warn("trade offer poll failed", "error", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Poll fetches the offers changed since the last poll and fires their events.
+func (m *TradeOfferManager) Poll(ctx context.Context) error {
+	events, err := m.poll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		m.logger.Info("trade offer event", "event", event.Type, "offer", event.Offer.ID, "state", event.Offer.State, "old_state", event.OldState)
+		if m.handler != nil {
+			m.handler(event)
+		}
+	}
+
+	return nil
+}
+
+// poll fetches and saves the offers, returning the events to fire. pollMu is
+// released before the handlers run so they can track the offers they send.
+func (m *TradeOfferManager) poll(ctx context.Context) ([]OfferEvent, error) {
+	m.pollMu.Lock()
+	defer m.pollMu.Unlock()
+
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	data := m.data.clone()
+	m.mu.Unlock()
+
+	started := m.now()
+	cutoff := data.Cutoff
+	if !cutoff.IsZero() {
+		cutoff = cutoff.Add(-pollCutoffSlack)
+	}
+
+	filter := uint32(TradeFilterSentOffers | TradeFilterRecvOffers | TradeFilterActiveOnly | TradeFilterUpdatedSince)
+	resp, err := m.service.GetTradeOffersContext(ctx, filter, cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []OfferEvent
+	if resp != nil {
+		events = append(events, diffOffers(data.Sent, resp.SentOffers, true)...)
+		events = append(events, diffOffers(data.Received, resp.ReceivedOffers, false)...)
+	}
+	data.Cutoff = started
+
+	for id := range data.Created {
+		if _, ok := data.Sent[id]; !ok {
+			delete(data.Created, id)
+		}
+	}
+	for i := range events {
+		if events[i].Type != OfferNew {
+			events[i].Meta = data.Created[events[i].Offer.ID].clone()
+		}
+	}
+
+	// saving before the handlers run means a crash can lose events but never
+	// delivers them twice
+	if err = m.save(data); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// TrackOffer registers an offer we just sent with its local metadata. Its
+// events carry the metadata and it is not reported as changed until it
+// moves past its current state.
+func (m *TradeOfferManager) TrackOffer(offer *TradeOffer, meta OfferMeta) error {
+	m.pollMu.Lock()
+	defer m.pollMu.Unlock()
+
+	if err := m.load(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	data := m.data.clone()
+	m.mu.Unlock()
+
+	data.Sent[offer.ID] = offer.State
+	data.Created[offer.ID] = meta.clone()
+
+	return m.save(data)
+}
+
+// OfferMeta returns the metadata of a tracked offer.
+func (m *TradeOfferManager) OfferMeta(id uint64) (OfferMeta, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	meta, ok := m.data.Created[id]
+	return meta.clone(), ok
+}
+
+// PollData returns a snapshot of the manager's state.
+func (m *TradeOfferManager) PollData() PollData {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.data.clone()
+}
+
+// diffOffers updates known with the polled offers and returns the events.
+// Finished offers missing from the poll are forgotten, steam only returns
+// them again if they change.
+func diffOffers(known map[uint64]uint8, offers []*TradeOffer, sent bool) []OfferEvent {
+	var events []OfferEvent
+	seen := make(map[uint64]bool, len(offers))
+
+	for _, offer := range offers {
+		seen[offer.ID] = true
+		old, ok := known[offer.ID]
+		known[offer.ID] = offer.State
+
+		if !ok {
+			if !sent && offer.State == TradeStateActive {
+				events = append(events, OfferEvent{Type: OfferNew, Offer: offer})
+			}
+			continue
+		}
+
+		if old == offer.State {
+			continue
+		}
+
+		changed := OfferReceivedChanged
+		if sent {
+			changed = OfferSentChanged
+		}
+		events = append(events, OfferEvent{Type: changed, Offer: offer, OldState: old})
+
+		switch offer.State {
+		case TradeStateAccepted:
+			events = append(events, OfferEvent{Type: OfferAccepted, Offer: offer, OldState: old})
+		case TradeStateExpired:
+			events = append(events, OfferEvent{Type: OfferExpired, Offer: offer, OldState: old})
+		case TradeStateInEscrow:
+			events = append(events, OfferEvent{Type: OfferInEscrow, Offer: offer, OldState: old})
+		}
+	}
+
+	for id, state := range known {
+		if !seen[id] && !offerActive(state) {
+			delete(known, id)
+		}
+	}
+
+	return events
+}
+
+func offerActive(state uint8) bool {
+	return state == TradeStateActive ||
+		state == TradeStateCreatedNeedsConfirmation ||
+		state == TradeStateInEscrow
+}
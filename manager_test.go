@@ -0,0 +1,104 @@
+package steam_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zergu1ar/steam"
+	"github.com/zergu1ar/steam/steamtest"
+)
+
+type recordedEvent struct {
+	Type  steam.OfferEventType
+	Offer uint64
+}
+
+func TestManagerEvents(t *testing.T) {
+	trades := steamtest.NewTradeOfferService()
+	id := trades.AddOffer(steam.TradeOffer{Partner: 1})
+
+	var events []recordedEvent
+	m := steam.NewTradeOfferManager(trades, func(event steam.OfferEvent) {
+		events = append(events, recordedEvent{event.Type, event.Offer.ID})
+	})
+
+	if err := m.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0] != (recordedEvent{steam.OfferNew, id}) {
+		t.Fatalf("first poll fired %v", events)
+	}
+
+	events = nil
+	trades.SetOfferState(id, steam.TradeStateAccepted)
+	if err := m.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []recordedEvent{{steam.OfferReceivedChanged, id}, {steam.OfferAccepted, id}}
+	if len(events) != len(want) || events[0] != want[0] || events[1] != want[1] {
+		t.Fatalf("second poll fired %v, want %v", events, want)
+	}
+}
+
+func TestManagerHandlerTracksOffers(t *testing.T) {
+	trades := steamtest.NewTradeOfferService()
+	trades.AddOffer(steam.TradeOffer{Partner: 1})
+
+	var m *steam.TradeOfferManager
+	var counter *steam.TradeOffer
+	var sentEvents []steam.OfferEvent
+	m = steam.NewTradeOfferManager(trades, func(event steam.OfferEvent) {
+		switch event.Type {
+		case steam.OfferNew:
+			counter = &steam.TradeOffer{}
+			if err := trades.SendTradeOfferContext(context.Background(), counter, steam.SteamID(76561197960265729), ""); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := m.TrackOffer(counter, steam.OfferMeta{"reason": "counter"}); err != nil {
+				t.Error(err)
+			}
+		case steam.OfferSentChanged:
+			sentEvents = append(sentEvents, event)
+		}
+	})
+
+	poll := func() {
+		t.Helper()
+
+		done := make(chan error, 1)
+		go func() { done <- m.Poll(context.Background()) }()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("poll did not return, handler deadlocked")
+		}
+	}
+
+	poll()
+	if counter == nil {
+		t.Fatal("handler did not send the counter offer")
+	}
+	if meta, ok := m.OfferMeta(counter.ID); !ok || meta["reason"] != "counter" {
+		t.Fatalf("tracked meta %v, %v", meta, ok)
+	}
+
+	trades.SetOfferState(counter.ID, steam.TradeStateDeclined)
+	poll()
+	if len(sentEvents) != 1 || sentEvents[0].Offer.ID != counter.ID || sentEvents[0].Meta["reason"] != "counter" {
+		t.Fatalf("sent offer events %+v", sentEvents)
+	}
+}
+
+func TestManagerRunInvalidInterval(t *testing.T) {
+	m := steam.NewTradeOfferManager(steamtest.NewTradeOfferService(), nil, steam.WithPollInterval(0))
+	if err := m.Run(context.Background()); err != steam.InvalidPollIntervalError {
+		t.Fatalf("got %v, want %v", err, steam.InvalidPollIntervalError)
+	}
+}
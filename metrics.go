@@ -0,0 +1,42 @@
+package steam
+
+import "time"
+
+// Metrics receives instrumentation events from the client. Implementations
+// must be safe for concurrent use, see the steamprom package for a
+// Prometheus adapter.
+type Metrics interface {
+	// ObserveRequest is called once per HTTP attempt. status is 0 when the
+	// request failed before a response was received and result is
+	// EResultInvalid when steam did not send one.
+	ObserveRequest(class EndpointClass, method string, status int, result EResult, latency time.Duration)
+	ObserveRateLimitWait(class EndpointClass, wait time.Duration)
+	// SetPendingConfirmations reports the depth of an account's confirmation
+	// queue, the number of confirmations returned by its last
+	// GetConfirmations call.
+	SetPendingConfirmations(account string, n int)
+	// ObserveLogin is called after every login attempt, method is one of
+	// LoginMethodPassword, LoginMethodRefreshToken or LoginMethodLegacy.
+	ObserveLogin(method string, err error)
+	// ObserveSessionRenewal is called after the access token is renewed or
+	// the session checker logged in again, kind is SessionRenewalAccessToken
+	// or SessionRenewalRelogin.
+	ObserveSessionRenewal(kind string, err error)
+}
+
+const (
+	LoginMethodPassword     = "password"
+	LoginMethodRefreshToken = "refresh_token"
+	LoginMethodLegacy       = "legacy"
+
+	SessionRenewalAccessToken = "access_token"
+	SessionRenewalRelogin     = "relogin"
+)
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveRequest(EndpointClass, string, int, EResult, time.Duration) {}
+func (nopMetrics) ObserveRateLimitWait(EndpointClass, time.Duration)                 {}
+func (nopMetrics) SetPendingConfirmations(string, int)                               {}
+func (nopMetrics) ObserveLogin(string, error)                                        {}
+func (nopMetrics) ObserveSessionRenewal(string, error)                               {}
@@ -0,0 +1,48 @@
+package steam_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zergu1ar/steam"
+	"github.com/zergu1ar/steam/steamtest"
+)
+
+type pendingMetrics struct {
+	mu      sync.Mutex
+	pending map[string]int
+}
+
+func (m *pendingMetrics) ObserveRequest(steam.EndpointClass, string, int, steam.EResult, time.Duration) {
+}
+func (m *pendingMetrics) ObserveRateLimitWait(steam.EndpointClass, time.Duration) {}
+func (m *pendingMetrics) ObserveLogin(string, error)                              {}
+func (m *pendingMetrics) ObserveSessionRenewal(string, error)                     {}
+
+func (m *pendingMetrics) SetPendingConfirmations(account string, n int) {
+	m.mu.Lock()
+	m.pending[account] = n
+	m.mu.Unlock()
+}
+
+func TestPendingConfirmationsPerAccount(t *testing.T) {
+	srv := newServer(t)
+	metrics := &pendingMetrics{pending: make(map[string]int)}
+
+	busy := addBot(srv, "busy")
+	srv.AddConfirmation(steamtest.Confirmation{Owner: busy.SteamID})
+	srv.AddConfirmation(steamtest.Confirmation{Owner: busy.SteamID})
+	addBot(srv, "idle")
+
+	for _, username := range []string{"busy", "idle"} {
+		client := login(t, srv, username, steam.WithMetrics(metrics))
+		if _, err := client.GetConfirmations(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if metrics.pending["busy"] != 2 || metrics.pending["idle"] != 0 {
+		t.Fatalf("pending confirmations %v", metrics.pending)
+	}
+}
@@ -1,5 +1,13 @@
 package steam
 
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
 type TradeOffer struct {
 	ID                 uint64      `json:"tradeofferid,string"`
 	Partner            uint32      `json:"accountid_other"`
@@ -17,18 +25,124 @@ type TradeOffer struct {
 	IsOurOffer         bool        `json:"is_our_offer"`
 }
 
-func (offer *TradeOffer) Send(c *Client, sid SteamID, token string) error {
-	return c.SendTradeOffer(offer, sid, token)
+func (offer *TradeOffer) Send(c TradeOfferService, sid SteamID, token string) error {
+	return c.SendTradeOfferContext(context.Background(), offer, sid, token)
 }
 
-func (offer *TradeOffer) Accept(c *Client) error {
-	return c.AcceptTradeOffer(offer.ID)
+func (offer *TradeOffer) Accept(c TradeOfferService) error {
+	return c.AcceptTradeOfferContext(context.Background(), offer.ID)
 }
 
-func (offer *TradeOffer) Cancel(c *Client) error {
+func (offer *TradeOffer) Cancel(c TradeOfferService) error {
 	if offer.IsOurOffer {
-		return c.CancelTradeOffer(offer.ID)
+		return c.CancelTradeOfferContext(context.Background(), offer.ID)
+	}
+
+	return c.DeclineTradeOfferContext(context.Background(), offer.ID)
+}
+
+// ConfirmPolicy controls how long SendTradeOfferAndConfirm looks for the
+// mobile confirmation of a sent offer, steam may list it a few seconds late.
+type ConfirmPolicy struct {
+	// MaxAttempts counts the first GetConfirmations call.
+	MaxAttempts int
+	Interval    time.Duration
+}
+
+const (
+	DefaultConfirmAttempts = 5
+	DefaultConfirmInterval = 3 * time.Second
+)
+
+func (c *Client) SendTradeOfferAndConfirm(offer *TradeOffer, sid SteamID, token string) error {
+	return c.SendTradeOfferAndConfirmContext(context.Background(), offer, sid, token)
+}
+
+// SendTradeOfferAndConfirmContext sends offer and accepts its mobile
+// confirmation when steam asks for one, looking for it as set by
+// WithConfirmPolicy. The offer stays sent when confirming fails, the
+// returned *OfferConfirmationError carries its id.
+func (c *Client) SendTradeOfferAndConfirmContext(ctx context.Context, offer *TradeOffer, sid SteamID, token string) error {
+	if err := c.SendTradeOfferContext(ctx, offer, sid, token); err != nil {
+		return err
+	}
+
+	return ConfirmSentOffer(ctx, c, c.confirmPolicy, offer)
+}
+
+// ConfirmSentOffer accepts the mobile confirmation of an offer that was just
+// sent, if it needs one. Confirmations are fetched until the offer's shows up
+// or policy.MaxAttempts is reached; failing to fetch them is retried when
+// the error is transient, the answer itself is never retried since steam may
+// have applied it.
+func ConfirmSentOffer(ctx context.Context, confirmations ConfirmationService, policy ConfirmPolicy, offer *TradeOffer) error {
+	if offer.State != TradeStateCreatedNeedsConfirmation {
+		return nil
+	}
+
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	err := ConfirmationsNotFoundError
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			if err := sleepContext(ctx, policy.Interval); err != nil {
+				return &OfferConfirmationError{OfferID: offer.ID, Attempts: attempt - 1, Err: err}
+			}
+		}
+
+		var confirmation *Confirmation
+		confirmation, err = findOfferConfirmation(ctx, confirmations, offer.ID)
+		if err == nil {
+			if err = confirmations.AnswerConfirmationContext(ctx, confirmation, AnswerAllow); err != nil {
+				return &OfferConfirmationError{OfferID: offer.ID, Attempts: attempt, Err: err}
+			}
+
+			offer.State = TradeStateActive
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return &OfferConfirmationError{OfferID: offer.ID, Attempts: attempt, Err: ctx.Err()}
+		}
+
+		if !confirmRetryable(err) {
+			return &OfferConfirmationError{OfferID: offer.ID, Attempts: attempt, Err: err}
+		}
+	}
+
+	return &OfferConfirmationError{OfferID: offer.ID, Attempts: attempts, Err: err}
+}
+
+func findOfferConfirmation(ctx context.Context, confirmations ConfirmationService, offerID uint64) (*Confirmation, error) {
+	list, err := confirmations.GetConfirmationsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, confirmation := range list {
+		if confirmation.OfferID == offerID {
+			return confirmation, nil
+		}
+	}
+
+	return nil, ConfirmationsNotFoundError
+}
+
+// confirmRetryable reports whether fetching the confirmations again may find
+// the offer's: it was not listed yet, or steam or the network failed.
+func confirmRetryable(err error) bool {
+	if errors.Is(err, ConfirmationsNotFoundError) {
+		return true
+	}
+
+	var steamErr *SteamError
+	if errors.As(err, &steamErr) {
+		return steamErr.StatusCode == http.StatusTooManyRequests || steamErr.StatusCode >= http.StatusInternalServerError
 	}
 
-	return c.DeclineTradeOffer(offer.ID)
+	var netErr net.Error
+	return errors.As(err, &netErr)
 }
@@ -0,0 +1,130 @@
+package steam_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/zergu1ar/steam"
+	"github.com/zergu1ar/steam/steamtest"
+)
+
+var fastConfirm = steam.ConfirmPolicy{MaxAttempts: 3, Interval: time.Millisecond}
+
+func pendingOffer(id uint64) *steam.TradeOffer {
+	return &steam.TradeOffer{ID: id, State: steam.TradeStateCreatedNeedsConfirmation}
+}
+
+func TestConfirmSentOfferRetriesTransientErrors(t *testing.T) {
+	confirmations := steamtest.NewConfirmationService()
+	id := confirmations.AddConfirmation(steam.Confirmation{OfferID: 7})
+
+	calls := 0
+	confirmations.Err = func(method string, _ uint64) error {
+		if method != "GetConfirmations" {
+			return nil
+		}
+		if calls++; calls == 1 {
+			return &steam.SteamError{Result: steam.EResultServiceUnavailable, StatusCode: http.StatusServiceUnavailable}
+		}
+		return nil
+	}
+
+	offer := pendingOffer(7)
+	if err := steam.ConfirmSentOffer(context.Background(), confirmations, fastConfirm, offer); err != nil {
+		t.Fatal(err)
+	}
+
+	if answer, _ := confirmations.Answer(id); answer != steam.AnswerAllow || offer.State != steam.TradeStateActive {
+		t.Fatalf("answer %q, offer state %d", answer, offer.State)
+	}
+}
+
+func TestConfirmSentOfferNotFound(t *testing.T) {
+	offer := pendingOffer(7)
+	err := steam.ConfirmSentOffer(context.Background(), steamtest.NewConfirmationService(), fastConfirm, offer)
+
+	var confirmErr *steam.OfferConfirmationError
+	if !errors.As(err, &confirmErr) || confirmErr.OfferID != 7 || confirmErr.Attempts != 3 || !errors.Is(err, steam.ConfirmationsNotFoundError) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestConfirmSentOfferDoesNotRetry(t *testing.T) {
+	for name, failing := range map[string]string{
+		"answer":        "AnswerConfirmation",
+		"not transient": "GetConfirmations",
+	} {
+		t.Run(name, func(t *testing.T) {
+			confirmations := steamtest.NewConfirmationService()
+			confirmations.AddConfirmation(steam.Confirmation{OfferID: 7})
+
+			// a 502 answer may still have been applied by steam
+			var failure error = &steam.SteamError{Result: steam.EResultServiceUnavailable, StatusCode: http.StatusBadGateway}
+			if failing == "GetConfirmations" {
+				failure = steam.InvalidSessionError
+			}
+
+			calls := 0
+			confirmations.Err = func(method string, _ uint64) error {
+				if method != failing {
+					return nil
+				}
+				calls++
+				return failure
+			}
+
+			err := steam.ConfirmSentOffer(context.Background(), confirmations, fastConfirm, pendingOffer(7))
+			var confirmErr *steam.OfferConfirmationError
+			if !errors.As(err, &confirmErr) || confirmErr.Attempts != 1 || calls != 1 {
+				t.Fatalf("got %v after %d calls", err, calls)
+			}
+		})
+	}
+}
+
+func TestSendTradeOfferAndConfirm(t *testing.T) {
+	srv := newServer(t)
+	bot := addBot(srv, "bot")
+	partner := srv.AddAccount(steamtest.Account{Username: "partner", Password: "secret"})
+	items := srv.AddItems(bot.SteamID, 730, 2, steamtest.Item{ClassID: 1})
+
+	client := login(t, srv, "bot", steam.WithConfirmPolicy(fastConfirm))
+	offer := &steam.TradeOffer{
+		SendItems: []*steam.EconItem{{AppID: 730, ContextID: 2, AssetID: items[0].AssetID, Amount: 1}},
+	}
+	if err := client.SendTradeOfferAndConfirm(offer, partner.SteamID, partner.TradeToken); err != nil {
+		t.Fatal(err)
+	}
+
+	if sent, _ := srv.Offer(offer.ID); sent.State != steam.TradeStateActive || offer.State != steam.TradeStateActive {
+		t.Fatalf("offer state %d on the server, %d locally", sent.State, offer.State)
+	}
+}
+
+func TestSendTradeOfferAndConfirmRetriesFailedFetch(t *testing.T) {
+	srv := newServer(t)
+	bot := addBot(srv, "bot")
+	partner := srv.AddAccount(steamtest.Account{Username: "partner", Password: "secret"})
+	items := srv.AddItems(bot.SteamID, 730, 2, steamtest.Item{ClassID: 1})
+
+	client := login(t, srv, "bot", steam.WithConfirmPolicy(fastConfirm))
+
+	// enough failures to exhaust the retries of the first GetConfirmations
+	srv.Fail("/mobileconf/conf", steamtest.Failure{Status: 503, Times: 3})
+	offer := &steam.TradeOffer{
+		SendItems: []*steam.EconItem{{AppID: 730, ContextID: 2, AssetID: items[0].AssetID, Amount: 1}},
+	}
+	if err := client.SendTradeOfferAndConfirm(offer, partner.SteamID, partner.TradeToken); err != nil {
+		t.Fatal(err)
+	}
+
+	if hits := srv.Hits("/mobileconf/conf"); hits != 4 {
+		t.Fatalf("%d confirmation fetches, want 4", hits)
+	}
+	if sent, _ := srv.Offer(offer.ID); sent.State != steam.TradeStateActive {
+		t.Fatalf("offer state %d, want %d", sent.State, steam.TradeStateActive)
+	}
+}
@@ -0,0 +1,113 @@
+package steam
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// OfferMeta is local data kept with an offer we created, e.g. an order id.
+type OfferMeta map[string]string
+
+// PollData is what a TradeOfferManager remembers between polls. Restore it
+// with WithPollData or WithPollDataStore after a restart so old offers do
+// not fire events again.
+type PollData struct {
+	// Cutoff is when the last successful poll started.
+	Cutoff   time.Time        `json:"cutoff"`
+	Sent     map[uint64]uint8 `json:"sent"`
+	Received map[uint64]uint8 `json:"received"`
+	// Created holds the offers registered with TrackOffer.
+	Created map[uint64]OfferMeta `json:"created,omitempty"`
+}
+
+func (d PollData) clone() PollData {
+	clone := PollData{
+		Cutoff:   d.Cutoff,
+		Sent:     make(map[uint64]uint8, len(d.Sent)),
+		Received: make(map[uint64]uint8, len(d.Received)),
+		Created:  make(map[uint64]OfferMeta, len(d.Created)),
+	}
+	for id, state := range d.Sent {
+		clone.Sent[id] = state
+	}
+	for id, state := range d.Received {
+		clone.Received[id] = state
+	}
+	for id, meta := range d.Created {
+		clone.Created[id] = meta.clone()
+	}
+
+	return clone
+}
+
+func (m OfferMeta) clone() OfferMeta {
+	if m == nil {
+		return nil
+	}
+
+	clone := make(OfferMeta, len(m))
+	for key, value := range m {
+		clone[key] = value
+	}
+
+	return clone
+}
+
+// PollDataStore persists the poll data of a TradeOfferManager. Load returns
+// PollDataNotFoundError when nothing was saved yet.
+type PollDataStore interface {
+	Load() (PollData, error)
+	Save(data PollData) error
+}
+
+// FilePollDataStore keeps the poll data as JSON in Path.
+type FilePollDataStore struct {
+	Path string
+}
+
+func NewFilePollDataStore(path string) *FilePollDataStore {
+	return &FilePollDataStore{Path: path}
+}
+
+func (s *FilePollDataStore) Load() (PollData, error) {
+	var data PollData
+
+	raw, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return data, PollDataNotFoundError
+	}
+
+	if err != nil {
+		return data, err
+	}
+
+	err = json.Unmarshal(raw, &data)
+	return data, err
+}
+
+func (s *FilePollDataStore) Save(data PollData) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.Path), ".polldata-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.Path)
+}
@@ -0,0 +1,58 @@
+package steam_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/zergu1ar/steam"
+	"github.com/zergu1ar/steam/steamtest"
+)
+
+func TestFilePollDataStore(t *testing.T) {
+	store := steam.NewFilePollDataStore(filepath.Join(t.TempDir(), "polldata.json"))
+	if _, err := store.Load(); err != steam.PollDataNotFoundError {
+		t.Fatalf("got %v, want %v", err, steam.PollDataNotFoundError)
+	}
+
+	saved := steam.PollData{
+		Sent:     map[uint64]uint8{1: steam.TradeStateActive},
+		Received: map[uint64]uint8{2: steam.TradeStateAccepted},
+		Created:  map[uint64]steam.OfferMeta{1: {"order": "42"}},
+	}
+	if err := store.Save(saved); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Sent[1] != steam.TradeStateActive || loaded.Received[2] != steam.TradeStateAccepted || loaded.Created[1]["order"] != "42" {
+		t.Fatalf("loaded %+v", loaded)
+	}
+}
+
+func TestManagerRestoresPollData(t *testing.T) {
+	trades := steamtest.NewTradeOfferService()
+	trades.AddOffer(steam.TradeOffer{Partner: 1})
+	store := steam.NewFilePollDataStore(filepath.Join(t.TempDir(), "polldata.json"))
+
+	var fired int
+	handler := func(steam.OfferEvent) { fired++ }
+
+	if err := steam.NewTradeOfferManager(trades, handler, steam.WithPollDataStore(store)).Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fired != 1 {
+		t.Fatalf("%d events, want 1", fired)
+	}
+
+	// a restarted manager does not report the offer again
+	if err := steam.NewTradeOfferManager(trades, handler, steam.WithPollDataStore(store)).Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fired != 1 {
+		t.Fatalf("%d events after restart, want 1", fired)
+	}
+}
@@ -0,0 +1,219 @@
+package steam
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type EndpointClass string
+
+const (
+	EndpointCommunity  EndpointClass = "community"
+	EndpointInventory  EndpointClass = "inventory"
+	EndpointWebAPI     EndpointClass = "webapi"
+	EndpointTradeOffer EndpointClass = "tradeoffer"
+	EndpointMobileConf EndpointClass = "mobileconf"
+	EndpointLogin      EndpointClass = "login"
+)
+
+// Rate allows one request every Every with bursts of up to Burst requests.
+// A zero Every means unlimited.
+type Rate struct {
+	Every time.Duration
+	Burst int
+}
+
+// DefaultRateLimits keep a single account below the thresholds steam starts
+// answering with HTTP 429. Classes missing from the map are not throttled.
+var DefaultRateLimits = map[EndpointClass]Rate{
+	EndpointInventory:  {Every: 4 * time.Second, Burst: 3},
+	EndpointWebAPI:     {Every: 250 * time.Millisecond, Burst: 10},
+	EndpointTradeOffer: {Every: time.Second, Burst: 3},
+	EndpointMobileConf: {Every: confirmationDelay * time.Second, Burst: 1},
+}
+
+type LimiterStats struct {
+	Requests uint64
+	// Delayed counts requests that had to wait for a token.
+	Delayed   uint64
+	TotalWait time.Duration
+	MaxWait   time.Duration
+}
+
+// RateLimiter is a token bucket per endpoint class. It is safe for
+// concurrent use and may be shared by several clients with WithRateLimiter.
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[EndpointClass]*bucket
+}
+
+type bucket struct {
+	rate   Rate
+	tokens float64
+	last   time.Time
+	stats  LimiterStats
+}
+
+func NewRateLimiter(limits map[EndpointClass]Rate) *RateLimiter {
+	l := &RateLimiter{buckets: make(map[EndpointClass]*bucket)}
+	for class, rate := range limits {
+		l.SetRate(class, rate)
+	}
+
+	return l
+}
+
+func (l *RateLimiter) SetRate(class EndpointClass, rate Rate) {
+	if rate.Burst < 1 {
+		rate.Burst = 1
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[class]; ok {
+		b.rate = rate
+		if b.tokens > float64(rate.Burst) {
+			b.tokens = float64(rate.Burst)
+		}
+		return
+	}
+
+	l.buckets[class] = &bucket{
+		rate:   rate,
+		tokens: float64(rate.Burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a request of the given class may be sent.
+func (l *RateLimiter) Wait(ctx context.Context, class EndpointClass) error {
+	_, err := l.wait(ctx, class)
+	return err
+}
+
+// wait is Wait reporting how long the caller was delayed.
+func (l *RateLimiter) wait(ctx context.Context, class EndpointClass) (time.Duration, error) {
+	l.mu.Lock()
+	b, ok := l.buckets[class]
+	if !ok {
+		l.mu.Unlock()
+		return 0, nil
+	}
+
+	wait := b.reserve(time.Now())
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return 0, nil
+	}
+
+	if err := sleepContext(ctx, wait); err != nil {
+		l.mu.Lock()
+		b.tokens++
+		l.mu.Unlock()
+		return 0, err
+	}
+
+	return wait, nil
+}
+
+// Stats returns a snapshot of the counters of every throttled class.
+func (l *RateLimiter) Stats() map[EndpointClass]LimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := make(map[EndpointClass]LimiterStats, len(l.buckets))
+	for class, b := range l.buckets {
+		stats[class] = b.stats
+	}
+
+	return stats
+}
+
+// reserve takes a token, going into debt when there is none so waiters are
+// served in order, and returns how long the caller has to wait for it.
+func (b *bucket) reserve(now time.Time) time.Duration {
+	b.stats.Requests++
+	if b.rate.Every <= 0 {
+		return 0
+	}
+
+	b.tokens += float64(now.Sub(b.last)) / float64(b.rate.Every)
+	if b.tokens > float64(b.rate.Burst) {
+		b.tokens = float64(b.rate.Burst)
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	wait := time.Duration(-b.tokens * float64(b.rate.Every))
+	b.stats.Delayed++
+	b.stats.TotalWait += wait
+	if wait > b.stats.MaxWait {
+		b.stats.MaxWait = wait
+	}
+
+	return wait
+}
+
+// DefaultGroupRateLimits are the per IP limits used by a RateLimitGroup
+// created with nil limits.
+var DefaultGroupRateLimits = map[EndpointClass]Rate{
+	EndpointInventory:  {Every: 2 * time.Second, Burst: 5},
+	EndpointTradeOffer: {Every: 500 * time.Millisecond, Burst: 5},
+}
+
+// RateLimitGroup coordinates clients that share an egress identity (proxy
+// or IP). Every identity gets its own RateLimiter, applied on top of the
+// limiter of each client that joined the group with WithRateLimitGroup.
+type RateLimitGroup struct {
+	mu       sync.Mutex
+	limits   map[EndpointClass]Rate
+	limiters map[string]*RateLimiter
+}
+
+func NewRateLimitGroup(limits map[EndpointClass]Rate) *RateLimitGroup {
+	if limits == nil {
+		limits = DefaultGroupRateLimits
+	}
+
+	return &RateLimitGroup{
+		limits:   limits,
+		limiters: make(map[string]*RateLimiter),
+	}
+}
+
+// Limiter returns the limiter shared by every client using egress.
+func (g *RateLimitGroup) Limiter(egress string) *RateLimiter {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	l, ok := g.limiters[egress]
+	if !ok {
+		l = NewRateLimiter(g.limits)
+		g.limiters[egress] = l
+	}
+
+	return l
+}
+
+func (g *RateLimitGroup) Stats() map[string]map[EndpointClass]LimiterStats {
+	g.mu.Lock()
+	limiters := make(map[string]*RateLimiter, len(g.limiters))
+	for egress, l := range g.limiters {
+		limiters[egress] = l
+	}
+	g.mu.Unlock()
+
+	stats := make(map[string]map[EndpointClass]LimiterStats, len(limiters))
+	for egress, l := range limiters {
+		stats[egress] = l.Stats()
+	}
+
+	return stats
+}
@@ -0,0 +1,68 @@
+package steam_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zergu1ar/steam"
+)
+
+func TestRateLimitGroupSharedByClients(t *testing.T) {
+	srv := newServer(t)
+	group := steam.NewRateLimitGroup(map[steam.EndpointClass]steam.Rate{
+		steam.EndpointInventory: {Every: 200 * time.Millisecond, Burst: 1},
+	})
+
+	var clients []*steam.Client
+	for _, bot := range []struct{ username, egress string }{
+		{"bot1", "10.0.0.1"},
+		{"bot2", "10.0.0.1"},
+		{"bot3", "10.0.0.2"},
+	} {
+		addBot(srv, bot.username)
+		clients = append(clients, login(t, srv, bot.username,
+			steam.WithRateLimits(nil),
+			steam.WithRateLimitGroup(group, bot.egress),
+		))
+	}
+
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		wg.Add(1)
+		go func(client *steam.Client) {
+			defer wg.Done()
+			if _, err := client.GetInventory(client.GetSteamId(), 730, 2, false); err != nil {
+				t.Error(err)
+			}
+		}(client)
+	}
+	wg.Wait()
+
+	stats := group.Stats()
+	if shared := stats["10.0.0.1"][steam.EndpointInventory]; shared.Requests != 2 || shared.Delayed != 1 {
+		t.Fatalf("shared egress: %+v, want 2 requests with 1 delayed", shared)
+	}
+
+	if alone := stats["10.0.0.2"][steam.EndpointInventory]; alone.Requests != 1 || alone.Delayed != 0 {
+		t.Fatalf("separate egress: %+v, want 1 request with none delayed", alone)
+	}
+}
+
+func TestRateLimiterWaitCanceled(t *testing.T) {
+	limiter := steam.NewRateLimiter(map[steam.EndpointClass]steam.Rate{
+		steam.EndpointTradeOffer: {Every: time.Hour, Burst: 1},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := limiter.Wait(ctx, steam.EndpointTradeOffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := limiter.Wait(ctx, steam.EndpointTradeOffer); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,258 @@
+package steam
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried. Requests are retried
+// on network errors, HTTP 429 and 5xx; POST requests and GETs that change
+// state are retried on 429 only unless RetryUnsafe is set, since steam may
+// have processed them. A Retry-After header is honoured up to MaxBackoff.
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt, 1 disables retries.
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	RetryUnsafe bool
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  time.Second,
+	MaxBackoff:  30 * time.Second,
+}
+
+type request struct {
+	class   EndpointClass
+	method  string
+	url     string
+	form    url.Values
+	referer string
+	// ajax marks requests steam expects from its own javascript
+	ajax       bool
+	noRedirect bool
+	// unsafe marks GET requests that change state, e.g. answering a
+	// confirmation, they are retried like POST requests
+	unsafe bool
+}
+
+func (r *request) idempotent() bool {
+	return !r.unsafe && (r.method == http.MethodGet || r.method == http.MethodHead)
+}
+
+// do sends r through the client applying common headers and the retry
+// policy. The caller must close the response body.
+func (c *Client) do(ctx context.Context, r *request) (*http.Response, error) {
+	class := r.class
+	if class == "" {
+		class = EndpointCommunity
+		if strings.HasPrefix(r.url, c.endpoints.WebAPI) {
+			class = EndpointWebAPI
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err := c.wait(ctx, class); err != nil {
+			return nil, err
+		}
+
+		req, err := c.newRequest(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+
+		client := c.client
+		if r.noRedirect {
+			noRedirect := *c.client
+			noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			}
+			client = &noRedirect
+		}
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		c.observeRequest(r, class, resp, err, time.Since(start), attempt)
+		if err != nil {
+			err = redactError(err)
+		}
+
+		if c.proxy != nil && ctx.Err() == nil && (err != nil || resp.StatusCode == http.StatusTooManyRequests) {
+			proxy := c.proxy.rotate()
+			c.logger.Info("rotated proxy", "proxy", redactURL(proxy.String()))
+		}
+
+		wait, retry := c.retryPolicy.backoff(r, resp, err, attempt)
+		if !retry || ctx.Err() != nil {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		c.logger.Debug("retrying steam request", "url", redactURL(r.url), "attempt", attempt, "retry_in", wait)
+		if err = sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// observeRequest reports an attempt to the logger and metrics.
+func (c *Client) observeRequest(r *request, class EndpointClass, resp *http.Response, err error, latency time.Duration, attempt int) {
+	args := []interface{}{
+		"method", r.method,
+		"url", redactURL(r.url),
+		"class", class,
+		"attempt", attempt,
+		"latency", latency,
+	}
+
+	if err != nil {
+		c.metrics.ObserveRequest(class, r.method, 0, EResultInvalid, latency)
+		c.logger.Debug("steam request failed", append(args, "error", redactError(err))...)
+		return
+	}
+
+	result := EResultInvalid
+	args = append(args, "status", resp.StatusCode)
+	if header := resp.Header.Get("x-eresult"); header != "" {
+		result = parseEResult(header)
+		args = append(args, "eresult", result)
+	}
+
+	c.metrics.ObserveRequest(class, r.method, resp.StatusCode, result, latency)
+	c.logger.Debug("steam request", args...)
+}
+
+// wait takes a token from the client's limiter and, when it joined one, from
+// the limiter of its rate limit group.
+func (c *Client) wait(ctx context.Context, class EndpointClass) error {
+	wait, err := c.limiter.wait(ctx, class)
+	if err != nil {
+		return err
+	}
+
+	if c.rateGroup != nil {
+		groupWait, err := c.rateGroup.Limiter(c.egressKey()).wait(ctx, class)
+		if err != nil {
+			return err
+		}
+		wait += groupWait
+	}
+
+	c.metrics.ObserveRateLimitWait(class, wait)
+	return nil
+}
+
+func (c *Client) newRequest(ctx context.Context, r *request) (*http.Request, error) {
+	var body io.Reader
+	if r.form != nil {
+		body = strings.NewReader(r.form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.method, r.url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", c.useragent)
+	req.Header.Set("Accept", "*/*")
+
+	if r.form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
+	}
+
+	if r.ajax {
+		req.Header.Set("X-Requested-With", "XMLHttpRequest")
+	}
+
+	if strings.HasPrefix(r.url, c.endpoints.Community) {
+		req.AddCookie(&http.Cookie{Name: "Steam_Language", Value: c.language})
+
+		if r.method != http.MethodGet {
+			req.Header.Set("Origin", c.endpoints.Community)
+		}
+
+		referer := r.referer
+		if referer == "" {
+			referer = c.endpoints.Community + "/"
+		}
+		req.Header.Set("Referer", referer)
+	} else if r.referer != "" {
+		req.Header.Set("Referer", r.referer)
+	}
+
+	return req, nil
+}
+
+// backoff reports whether the attempt should be retried and how long to
+// wait before doing so.
+func (p RetryPolicy) backoff(r *request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+
+	switch {
+	case err != nil:
+		if !r.idempotent() && !p.RetryUnsafe {
+			return 0, false
+		}
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case resp.StatusCode >= http.StatusInternalServerError:
+		if !r.idempotent() && !p.RetryUnsafe {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	if resp != nil {
+		if wait, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+				wait = p.MaxBackoff
+			}
+			return wait, true
+		}
+	}
+
+	wait := p.MinBackoff << uint(attempt-1)
+	if wait <= 0 || (p.MaxBackoff > 0 && wait > p.MaxBackoff) {
+		wait = p.MaxBackoff
+	}
+
+	// jitter keeps clients sharing an IP from retrying in lockstep
+	if half := int64(wait / 2); half > 0 {
+		wait = time.Duration(half + rand.Int63n(half))
+	}
+
+	return wait, true
+}
+
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
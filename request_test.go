@@ -0,0 +1,73 @@
+package steam_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zergu1ar/steam"
+	"github.com/zergu1ar/steam/steamtest"
+)
+
+func TestRetryTransientFailures(t *testing.T) {
+	srv := newServer(t)
+	addBot(srv, "bot")
+	client := login(t, srv, "bot")
+
+	srv.Fail("/mobileconf/conf", steamtest.Failure{Status: 503})
+	srv.Fail("/mobileconf/conf", steamtest.Failure{Drop: true})
+	if _, err := client.GetConfirmations(); err != nil {
+		t.Fatal(err)
+	}
+
+	if hits := srv.Hits("/mobileconf/conf"); hits != 3 {
+		t.Fatalf("%d requests, want 3", hits)
+	}
+}
+
+func TestNoRetryOfStateChangingRequests(t *testing.T) {
+	srv := newServer(t)
+	bot := addBot(srv, "bot")
+	srv.AddConfirmation(steamtest.Confirmation{Owner: bot.SteamID})
+	client := login(t, srv, "bot")
+
+	confirmations, err := client.GetConfirmations()
+	if err != nil || len(confirmations) != 1 {
+		t.Fatal(confirmations, err)
+	}
+
+	srv.Fail("/mobileconf/ajaxop", steamtest.Failure{Status: 502})
+	if err = client.AnswerConfirmation(confirmations[0], steam.AnswerAllow); err == nil {
+		t.Fatal("expected the 502 to be returned")
+	}
+
+	if hits := srv.Hits("/mobileconf/ajaxop"); hits != 1 {
+		t.Fatalf("%d requests, want 1", hits)
+	}
+
+	// 429 means steam did not process the request, it is always retried
+	srv.Fail("/mobileconf/ajaxop", steamtest.Failure{Status: 429})
+	if err = client.AnswerConfirmation(confirmations[0], steam.AnswerAllow); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetryAfterCappedAtMaxBackoff(t *testing.T) {
+	srv := newServer(t)
+	addBot(srv, "bot")
+	client := login(t, srv, "bot", steam.WithRetryPolicy(steam.RetryPolicy{
+		MaxAttempts: 2,
+		MinBackoff:  10 * time.Millisecond,
+		MaxBackoff:  50 * time.Millisecond,
+	}))
+
+	srv.Fail("/mobileconf/conf", steamtest.Failure{Status: 429, RetryAfter: 3600})
+
+	start := time.Now()
+	if _, err := client.GetConfirmations(); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("waited %v for Retry-After", elapsed)
+	}
+}
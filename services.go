@@ -0,0 +1,35 @@
+package steam
+
+import (
+	"context"
+	"time"
+)
+
+// TradeOfferService is the trade offer part of Client, depend on it instead
+// of *Client to substitute a fake in tests. The services only list the
+// Context methods, Client's plain methods are shorthands for them.
+type TradeOfferService interface {
+	GetTradeOfferContext(ctx context.Context, id uint64) (*TradeOffer, error)
+	GetTradeOffersContext(ctx context.Context, filter uint32, timeCutOff time.Time) (*TradeOfferResponse, error)
+	SendTradeOfferContext(ctx context.Context, offer *TradeOffer, sid SteamID, token string) error
+	AcceptTradeOfferContext(ctx context.Context, id uint64) error
+	DeclineTradeOfferContext(ctx context.Context, id uint64) error
+	CancelTradeOfferContext(ctx context.Context, id uint64) error
+}
+
+type InventoryService interface {
+	GetInventoryContext(ctx context.Context, sid SteamID, appID, contextID uint64, tradableOnly bool) ([]InventoryItem, error)
+	GetFilterableInventoryContext(ctx context.Context, sid SteamID, appID, contextID uint64, filters []Filter) ([]InventoryItem, error)
+	GetInventoryAppStatsContext(ctx context.Context, sid SteamID) (map[string]InventoryAppStats, error)
+}
+
+type ConfirmationService interface {
+	GetConfirmationsContext(ctx context.Context) ([]*Confirmation, error)
+	AnswerConfirmationContext(ctx context.Context, confirmation *Confirmation, answer string) error
+}
+
+var (
+	_ TradeOfferService   = (*Client)(nil)
+	_ InventoryService    = (*Client)(nil)
+	_ ConfirmationService = (*Client)(nil)
+)
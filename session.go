@@ -1,11 +1,293 @@
 package steam
 
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const sessionVersion = 1
+
+type sessionCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Domain   string `json:"domain,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Expires  int64  `json:"expires,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+	HttpOnly bool   `json:"http_only,omitempty"`
+}
+
+type sessionData struct {
+	Version     int                        `json:"version"`
+	SteamID     SteamID                    `json:"steamid,string"`
+	ID          string                     `json:"sessionid"`
+	DeviceID    string                     `json:"device_id"`
+	Auth        string                     `json:"auth,omitempty"`
+	TokenSecure string                     `json:"token_secure,omitempty"`
+	WebCookie   string                     `json:"webcookie,omitempty"`
+	Access      string                     `json:"access_token,omitempty"`
+	Refresh     string                     `json:"refresh_token,omitempty"`
+	APIKey      string                     `json:"api_key,omitempty"`
+	Cookies     map[string][]sessionCookie `json:"cookies"`
+}
+
+const (
+	sessionProbeTimeout = 30 * time.Second
+	reloginMinBackoff   = 30 * time.Second
+	reloginMaxBackoff   = 30 * time.Minute
+)
+
+// checkSession probes the session every sessionCheckInterval and logs in
+// again when steam no longer accepts it.
 func (c *Client) checkSession() {
-	// todo check current session
+	if c.sessionCheckInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.sessionCheckInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
+		case <-ticker.C:
+			if c.getSession() == nil {
+				continue
+			}
+
+			if c.probeSession() {
+				continue
+			}
+
+			c.logger.Info("steam session expired", "account", c.credentials.Username)
+			if c.onSessionExpired != nil {
+				c.onSessionExpired()
+			}
+
+			if err := c.relogin(); err != nil {
+				return
+			}
+
+			c.logger.Info("steam session renewed", "account", c.credentials.Username)
+			if c.onSessionRenewed != nil {
+				c.onSessionRenewed()
+			}
 		case <-c.ctx.Done():
 			return
 		}
 	}
 }
+
+// probeSession reports whether the session is alive, network errors are
+// not treated as an expired session.
+func (c *Client) probeSession() bool {
+	ctx, cancel := context.WithTimeout(c.ctx, sessionProbeTimeout)
+	defer cancel()
+
+	alive, err := c.IsSessionAliveContext(ctx)
+	if err != nil {
+		c.logger.Warn("unable to check steam session", "error", err)
+		return true
+	}
+
+	return alive
+}
+
+// relogin retries login with exponential backoff until it succeeds or the
+// client is destroyed. The refresh token is tried before the password.
+func (c *Client) relogin() error {
+	backoff := reloginMinBackoff
+	for {
+		err := InvalidTokenError
+		if token := c.RefreshToken(); token != "" {
+			err = c.LoginWithRefreshTokenContext(c.ctx, token)
+		}
+
+		if err != nil && c.credentials.Password != "" {
+			err = c.login(c.ctx)
+		}
+
+		c.metrics.ObserveSessionRenewal(SessionRenewalRelogin, err)
+		if err == nil {
+			return nil
+		}
+
+		c.logger.Warn("steam relogin failed", "error", err, "retry_in", backoff)
+		if err = sleepContext(c.ctx, backoff); err != nil {
+			return err
+		}
+
+		if backoff *= 2; backoff > reloginMaxBackoff {
+			backoff = reloginMaxBackoff
+		}
+	}
+}
+
+// ExportSession serializes the logged in session, cookies and web api key
+// so it can be restored later with RestoreSession.
+func (c *Client) ExportSession() ([]byte, error) {
+	session := c.getSession()
+	if session == nil {
+		return nil, InvalidSessionError
+	}
+
+	data := sessionData{
+		Version:     sessionVersion,
+		SteamID:     session.SteamID,
+		ID:          session.ID,
+		DeviceID:    session.DeviceID,
+		Auth:        session.Auth,
+		TokenSecure: session.TokenSecure,
+		WebCookie:   session.WebCookie,
+		Access:      session.AccessToken,
+		Refresh:     session.RefreshToken,
+		APIKey:      c.apiKey,
+		Cookies:     c.jar.saved(),
+	}
+
+	return json.Marshal(data)
+}
+
+func (c *Client) RestoreSession(data []byte) error {
+	return c.RestoreSessionContext(context.Background(), data)
+}
+
+// RestoreSessionContext loads a session created by ExportSession and checks
+// that Steam still accepts it. The previous state is kept if it does not.
+func (c *Client) RestoreSessionContext(ctx context.Context, data []byte) error {
+	var session sessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return err
+	}
+
+	if session.Version != sessionVersion {
+		return UnsupportedSessionVersionError
+	}
+
+	if session.ID == "" || session.SteamID == 0 {
+		return InvalidSessionError
+	}
+
+	jar, err := loadJar(session.Cookies)
+	if err != nil {
+		return err
+	}
+
+	prevJar, prevSession, prevKey := c.jar.state(), c.getSession(), c.apiKey
+
+	c.jar.setState(jar)
+	c.setSession(&OAuth{
+		ID:          session.ID,
+		DeviceID:    session.DeviceID,
+		SteamID:     session.SteamID,
+		Auth:        session.Auth,
+		TokenSecure: session.TokenSecure,
+		WebCookie:   session.WebCookie,
+
+		AccessToken:  session.Access,
+		RefreshToken: session.Refresh,
+	})
+	c.apiKey = session.APIKey
+
+	alive, err := c.IsSessionAliveContext(ctx)
+	if err == nil && !alive {
+		err = InvalidSessionError
+	}
+
+	if err != nil {
+		c.jar.setState(prevJar)
+		c.apiKey = prevKey
+		c.setSession(prevSession)
+		return err
+	}
+
+	return nil
+}
+
+func (c *Client) IsSessionAlive() (bool, error) {
+	return c.IsSessionAliveContext(context.Background())
+}
+
+// IsSessionAliveContext asks the community for the current user's profile,
+// steam redirects to the login page when the session is gone. Any answer
+// other than the profile or a redirect is returned as a *SteamError, a
+// throttled probe says nothing about the session.
+func (c *Client) IsSessionAliveContext(ctx context.Context) (bool, error) {
+	if c.getSession() == nil {
+		return false, nil
+	}
+
+	resp, err := c.do(ctx, &request{
+		method:     http.MethodGet,
+		url:        c.endpoints.community("/my/"),
+		noRedirect: true,
+	})
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return true, nil
+	case resp.StatusCode >= 300 && resp.StatusCode < 400 && resp.Header.Get("Location") != "":
+		return !strings.Contains(resp.Header.Get("Location"), "/login"), nil
+	}
+
+	return false, newSteamError(resp, "")
+}
+
+// restoreStoredSession reports whether a live session was loaded from the
+// session store, falling back to the stored refresh token.
+func (c *Client) restoreStoredSession(ctx context.Context) bool {
+	if c.sessionStore == nil {
+		return false
+	}
+
+	data, err := c.sessionStore.Load(c.credentials.Username)
+	if err != nil {
+		if err != SessionNotFoundError {
+			c.logger.Warn("unable to load stored session", "error", err)
+		}
+		return false
+	}
+
+	if err = c.RestoreSessionContext(ctx, data); err == nil {
+		return true
+	}
+	c.logger.Info("stored session is not usable", "error", err)
+
+	var stored sessionData
+	if err = json.Unmarshal(data, &stored); err != nil || stored.Refresh == "" {
+		return false
+	}
+
+	if err = c.LoginWithRefreshTokenContext(ctx, stored.Refresh); err != nil {
+		c.logger.Info("stored refresh token is not usable", "error", err)
+		return false
+	}
+
+	if c.apiKey == "" {
+		c.apiKey = stored.APIKey
+		c.saveSession()
+	}
+
+	return true
+}
+
+func (c *Client) saveSession() {
+	if c.sessionStore == nil {
+		return
+	}
+
+	data, err := c.ExportSession()
+	if err == nil {
+		err = c.sessionStore.Save(c.credentials.Username, data)
+	}
+
+	if err != nil {
+		c.logger.Warn("unable to save session", "error", err)
+	}
+}
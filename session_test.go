@@ -0,0 +1,146 @@
+package steam_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zergu1ar/steam"
+	"github.com/zergu1ar/steam/steamtest"
+)
+
+const passwordLoginPath = "/IAuthenticationService/BeginAuthSessionViaCredentials/v1/"
+
+func TestExportRestoreSession(t *testing.T) {
+	srv := newServer(t)
+	addBot(srv, "bot")
+
+	data, err := login(t, srv, "bot").ExportSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := srv.Client("bot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Destroy()
+
+	if err = client.RestoreSession(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = client.GetConfirmations(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsSessionAlive(t *testing.T) {
+	srv := newServer(t)
+	addBot(srv, "bot")
+	client := login(t, srv, "bot")
+
+	if alive, err := client.IsSessionAlive(); !alive || err != nil {
+		t.Fatalf("fresh session: alive=%v, %v", alive, err)
+	}
+
+	srv.Fail("/my/", steamtest.Failure{Status: 429, Times: -1})
+	if alive, err := client.IsSessionAlive(); !errors.Is(err, steam.EResultRateLimitExceeded) {
+		t.Fatalf("throttled probe: alive=%v, %v, want a rate limit error", alive, err)
+	}
+	srv.ClearFailures()
+
+	srv.ExpireSessions("bot")
+	if alive, err := client.IsSessionAlive(); alive || err != nil {
+		t.Fatalf("expired session: alive=%v, %v", alive, err)
+	}
+}
+
+// sessionCallbacks records the session check callbacks of a client.
+type sessionCallbacks struct {
+	expired chan struct{}
+	renewed chan struct{}
+}
+
+func newSessionCallbacks() *sessionCallbacks {
+	return &sessionCallbacks{expired: make(chan struct{}, 10), renewed: make(chan struct{}, 10)}
+}
+
+func (c *sessionCallbacks) options() []steam.Option {
+	return []steam.Option{
+		steam.WithSessionCheck(50 * time.Millisecond),
+		steam.OnSessionExpired(func() { c.expired <- struct{}{} }),
+		steam.OnSessionRenewed(func() { c.renewed <- struct{}{} }),
+	}
+}
+
+func (c *sessionCallbacks) wait(t *testing.T, ch chan struct{}, what string) {
+	t.Helper()
+
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("session was not %s", what)
+	}
+}
+
+func TestSessionCheckRelogin(t *testing.T) {
+	for name, revoke := range map[string]bool{
+		"refresh token": false,
+		"password":      true,
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv := newServer(t)
+			addBot(srv, "bot")
+
+			callbacks := newSessionCallbacks()
+			client := login(t, srv, "bot", callbacks.options()...)
+			passwordLogins := srv.Hits(passwordLoginPath)
+
+			if revoke {
+				srv.RevokeRefreshTokens("bot")
+			}
+			srv.ExpireSessions("bot")
+
+			callbacks.wait(t, callbacks.expired, "reported expired")
+			callbacks.wait(t, callbacks.renewed, "renewed")
+
+			wantPasswordLogins := passwordLogins
+			if revoke {
+				wantPasswordLogins++
+			}
+			if hits := srv.Hits(passwordLoginPath); hits != wantPasswordLogins {
+				t.Fatalf("%d password logins, want %d", hits, wantPasswordLogins)
+			}
+
+			if _, err := client.GetConfirmations(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestSessionCheckIgnoresThrottledProbes(t *testing.T) {
+	srv := newServer(t)
+	addBot(srv, "bot")
+
+	callbacks := newSessionCallbacks()
+	login(t, srv, "bot", callbacks.options()...)
+	passwordLogins := srv.Hits(passwordLoginPath)
+
+	srv.Fail("/my/", steamtest.Failure{Status: 429, Times: -1})
+	deadline := time.Now().Add(time.Second)
+	for srv.Hits("/my/") < 3 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	select {
+	case <-callbacks.expired:
+		t.Fatal("a throttled probe was taken for an expired session")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if hits := srv.Hits(passwordLoginPath); hits != passwordLogins {
+		t.Fatalf("logged in again %d times", hits-passwordLogins)
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/zergu1ar/steam/steamotel
+
+go 1.15
+
+require (
+	github.com/zergu1ar/steam v0.0.0-20261018110819-65d57b9ec9d6
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/sdk v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
+)
+
+replace github.com/zergu1ar/steam => ../
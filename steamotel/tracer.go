@@ -0,0 +1,96 @@
+// Package steamotel exports steam client spans through OpenTelemetry.
+package steamotel
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/zergu1ar/steam"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/zergu1ar/steam"
+
+// Tracer implements steam.Tracer on top of an OpenTelemetry tracer.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// New creates a Tracer from provider, e.g. otel.GetTracerProvider() or an
+// sdktrace.TracerProvider using an in-memory exporter in tests.
+func New(provider trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: provider.Tracer(instrumentationName)}
+}
+
+func (t *Tracer) Start(ctx context.Context, name string, args ...interface{}) (context.Context, steam.Span) {
+	ctx, span := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes(args)...),
+	)
+
+	return ctx, &Span{span: span}
+}
+
+type Span struct {
+	span trace.Span
+}
+
+func (s *Span) SetAttributes(args ...interface{}) {
+	s.span.SetAttributes(attributes(args)...)
+}
+
+func (s *Span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+
+	s.span.End()
+}
+
+// attributes converts key-value pairs to attributes, keys that are not
+// strings and a trailing key without value are dropped.
+func attributes(args []interface{}) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok {
+			continue
+		}
+
+		attrs = append(attrs, keyValue(key, args[i+1]))
+	}
+
+	return attrs
+}
+
+func keyValue(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case uint32:
+		return attribute.Int64(key, int64(v))
+	case uint64:
+		if v <= math.MaxInt64 {
+			return attribute.Int64(key, int64(v))
+		}
+		return attribute.String(key, fmt.Sprint(v))
+	case float64:
+		return attribute.Float64(key, v)
+	case fmt.Stringer:
+		return attribute.String(key, v.String())
+	}
+
+	return attribute.String(key, fmt.Sprint(value))
+}
+
+var _ steam.Tracer = (*Tracer)(nil)
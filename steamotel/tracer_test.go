@@ -0,0 +1,133 @@
+package steamotel_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zergu1ar/steam"
+	"github.com/zergu1ar/steam/steamotel"
+	"github.com/zergu1ar/steam/steamtest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// lastSpan returns the last ended span called name.
+func lastSpan(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	spans := recorder.Ended()
+	for i := len(spans) - 1; i >= 0; i-- {
+		if spans[i].Name() == name {
+			return spans[i]
+		}
+	}
+
+	t.Fatalf("no %s span", name)
+	return nil
+}
+
+func checkAttributes(t *testing.T, span sdktrace.ReadOnlySpan, want map[attribute.Key]attribute.Value) {
+	t.Helper()
+
+	got := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		got[kv.Key] = kv.Value
+	}
+
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s: %s = %v, want %v", span.Name(), key, got[key].Emit(), value.Emit())
+		}
+	}
+}
+
+func TestTracer(t *testing.T) {
+	srv := steamtest.NewServer()
+	defer srv.Close()
+
+	bot := srv.AddAccount(steamtest.Account{
+		Username:       "bot",
+		Password:       "secret",
+		SharedSecret:   "c2hhcmVkc2VjcmV0MTIzNDU2Nzg=",
+		IdentitySecret: "aWRlbnRpdHlzZWNyZXQxMjM0NTY=",
+	})
+	partner := srv.AddAccount(steamtest.Account{Username: "partner", Password: "secret"})
+	items := srv.AddItems(bot.SteamID, 730, 2, steamtest.Item{ClassID: 1}, steamtest.Item{ClassID: 2})
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	client, err := srv.Client("bot", steam.WithTracer(steamotel.New(provider)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Destroy()
+
+	if err = client.Login(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.GetWebAPIKey(); err != nil {
+		t.Fatal(err)
+	}
+
+	offer := &steam.TradeOffer{
+		SendItems: []*steam.EconItem{{AppID: 730, ContextID: 2, AssetID: items[0].AssetID, Amount: 1}},
+	}
+	if err = client.SendTradeOffer(offer, partner.SteamID, partner.TradeToken); err != nil {
+		t.Fatal(err)
+	}
+
+	span := lastSpan(t, recorder, "steam.SendTradeOffer")
+	if span.SpanKind() != trace.SpanKindClient || span.Status().Code != codes.Unset {
+		t.Errorf("%s: kind %v, status %v", span.Name(), span.SpanKind(), span.Status())
+	}
+	checkAttributes(t, span, map[attribute.Key]attribute.Value{
+		"partner":          attribute.StringValue(partner.SteamID.ToString()),
+		"offer_id":         attribute.Int64Value(int64(offer.ID)),
+		"items_to_give":    attribute.IntValue(1),
+		"items_to_receive": attribute.IntValue(0),
+	})
+
+	if _, err = client.GetConfirmations(); err != nil {
+		t.Fatal(err)
+	}
+	checkAttributes(t, lastSpan(t, recorder, "steam.GetConfirmations"), map[attribute.Key]attribute.Value{
+		"confirmations": attribute.IntValue(1),
+	})
+
+	inventory, err := client.GetInventory(bot.SteamID, 730, 2, false)
+	if err != nil || len(inventory) == 0 {
+		t.Fatalf("got %d items, %v", len(inventory), err)
+	}
+	checkAttributes(t, lastSpan(t, recorder, "steam.GetInventoryPage"), map[attribute.Key]attribute.Value{
+		"steamid":    attribute.StringValue(bot.SteamID.ToString()),
+		"appid":      attribute.Int64Value(730),
+		"contextid":  attribute.Int64Value(2),
+		"items":      attribute.IntValue(len(inventory)),
+		"more_items": attribute.BoolValue(false),
+	})
+
+	srv.Fail("/mobileconf/conf", steamtest.Failure{Status: 503, Times: -1})
+	if _, err = client.GetConfirmations(); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	span = lastSpan(t, recorder, "steam.GetConfirmations")
+	if status := span.Status(); status.Code != codes.Error || status.Description != err.Error() {
+		t.Errorf("%s: status %v, want an error status for %v", span.Name(), status, err)
+	}
+	if events := span.Events(); len(events) != 1 || events[0].Name != "exception" {
+		t.Errorf("%s: events %+v, want the recorded error", span.Name(), events)
+	}
+
+	srv.Fail("/inventory/", steamtest.Failure{Status: 429, Body: "null", Times: -1})
+	if _, err = client.GetInventory(bot.SteamID, 730, 2, false); !errors.Is(err, steam.EResultRateLimitExceeded) {
+		t.Fatalf("got %v, want a rate limit error", err)
+	}
+	if status := lastSpan(t, recorder, "steam.GetInventoryPage").Status(); status.Code != codes.Error {
+		t.Errorf("steam.GetInventoryPage: status %v, want an error status", status)
+	}
+}
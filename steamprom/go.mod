@@ -0,0 +1,10 @@
+module github.com/zergu1ar/steam/steamprom
+
+go 1.15
+
+require (
+	github.com/prometheus/client_golang v1.11.1
+	github.com/zergu1ar/steam v0.0.0-20261018110819-65d57b9ec9d6
+)
+
+replace github.com/zergu1ar/steam => ../
@@ -0,0 +1,125 @@
+// Package steamprom reports steam client metrics to Prometheus.
+package steamprom
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zergu1ar/steam"
+)
+
+const namespace = "steam"
+
+// Metrics implements steam.Metrics, one instance may be shared by several
+// clients.
+type Metrics struct {
+	requests             *prometheus.CounterVec
+	requestDuration      *prometheus.HistogramVec
+	rateLimitWait        *prometheus.HistogramVec
+	pendingConfirmations *prometheus.GaugeVec
+	logins               *prometheus.CounterVec
+	loginFailures        *prometheus.CounterVec
+	sessionRenewals      *prometheus.CounterVec
+}
+
+// New creates the collectors and registers them with reg.
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "HTTP requests sent to steam by endpoint class, status and EResult.",
+		}, []string{"class", "method", "status", "eresult"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of HTTP requests sent to steam.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"class", "method"}),
+		rateLimitWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_wait_seconds",
+			Help:      "Time requests spent waiting for the rate limiter.",
+			Buckets:   []float64{0, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"class"}),
+		pendingConfirmations: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "pending_confirmations",
+			Help:      "Mobile confirmations returned by the last fetch, by account.",
+		}, []string{"account"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "login_attempts_total",
+			Help:      "Login attempts by method.",
+		}, []string{"method"}),
+		loginFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "login_failures_total",
+			Help:      "Failed login attempts by method.",
+		}, []string{"method"}),
+		sessionRenewals: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "session_renewals_total",
+			Help:      "Access token renewals and relogins by outcome.",
+		}, []string{"kind", "outcome"}),
+	}
+
+	collectors := []prometheus.Collector{
+		m.requests,
+		m.requestDuration,
+		m.rateLimitWait,
+		m.pendingConfirmations,
+		m.logins,
+		m.loginFailures,
+		m.sessionRenewals,
+	}
+	for _, collector := range collectors {
+		if err := reg.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Metrics) ObserveRequest(class steam.EndpointClass, method string, status int, result steam.EResult, latency time.Duration) {
+	eresult := ""
+	if result != steam.EResultInvalid {
+		eresult = result.String()
+	}
+
+	statusLabel := "error"
+	if status != 0 {
+		statusLabel = strconv.Itoa(status)
+	}
+
+	m.requests.WithLabelValues(string(class), method, statusLabel, eresult).Inc()
+	m.requestDuration.WithLabelValues(string(class), method).Observe(latency.Seconds())
+}
+
+func (m *Metrics) ObserveRateLimitWait(class steam.EndpointClass, wait time.Duration) {
+	m.rateLimitWait.WithLabelValues(string(class)).Observe(wait.Seconds())
+}
+
+func (m *Metrics) SetPendingConfirmations(account string, n int) {
+	m.pendingConfirmations.WithLabelValues(account).Set(float64(n))
+}
+
+func (m *Metrics) ObserveLogin(method string, err error) {
+	m.logins.WithLabelValues(method).Inc()
+	if err != nil {
+		m.loginFailures.WithLabelValues(method).Inc()
+	}
+}
+
+func (m *Metrics) ObserveSessionRenewal(kind string, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+
+	m.sessionRenewals.WithLabelValues(kind, outcome).Inc()
+}
+
+var _ steam.Metrics = (*Metrics)(nil)
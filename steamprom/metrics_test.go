@@ -0,0 +1,97 @@
+package steamprom_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/zergu1ar/steam"
+	"github.com/zergu1ar/steam/steamprom"
+	"github.com/zergu1ar/steam/steamtest"
+)
+
+func TestMetrics(t *testing.T) {
+	srv := steamtest.NewServer()
+	defer srv.Close()
+
+	srv.AddAccount(steamtest.Account{
+		Username:       "bot",
+		Password:       "secret",
+		SharedSecret:   "c2hhcmVkc2VjcmV0MTIzNDU2Nzg=",
+		IdentitySecret: "aWRlbnRpdHlzZWNyZXQxMjM0NTY=",
+	})
+
+	// log in without metrics so that only the requests below are counted
+	session, err := exportSession(srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reg := prometheus.NewRegistry()
+	metrics, err := steamprom.New(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// one attempt per call, so that every failure shows up once
+	client, err := srv.Client("bot", steam.WithMetrics(metrics), steam.WithRetryPolicy(steam.RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Destroy()
+
+	if err = client.RestoreSession(session); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = client.GetConfirmations(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a dropped connection has neither a status nor an eresult
+	srv.Fail("/mobileconf/conf", steamtest.Failure{Drop: true, Times: -1})
+	if _, err = client.GetConfirmations(); err == nil {
+		t.Fatal("expected an error for a dropped connection")
+	}
+	srv.ClearFailures()
+
+	srv.Fail("/mobileconf/conf", steamtest.Failure{Status: 429, EResult: steam.EResultRateLimitExceeded, Times: -1})
+	if _, err = client.GetConfirmations(); err == nil {
+		t.Fatal("expected an error for a throttled request")
+	}
+	srv.ClearFailures()
+
+	// NewClient syncs the time and RestoreSession probes the session, both
+	// count as webapi since every host is the test server
+	expected := `
+# HELP steam_pending_confirmations Mobile confirmations returned by the last fetch, by account.
+# TYPE steam_pending_confirmations gauge
+steam_pending_confirmations{account="bot"} 0
+# HELP steam_requests_total HTTP requests sent to steam by endpoint class, status and EResult.
+# TYPE steam_requests_total counter
+steam_requests_total{class="mobileconf",eresult="",method="GET",status="200"} 1
+steam_requests_total{class="mobileconf",eresult="",method="GET",status="error"} 1
+steam_requests_total{class="mobileconf",eresult="RateLimitExceeded",method="GET",status="429"} 1
+steam_requests_total{class="webapi",eresult="",method="GET",status="200"} 1
+steam_requests_total{class="webapi",eresult="",method="POST",status="200"} 1
+`
+	err = testutil.GatherAndCompare(reg, strings.NewReader(expected), "steam_requests_total", "steam_pending_confirmations")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exportSession(srv *steamtest.Server) ([]byte, error) {
+	client, err := srv.Client("bot")
+	if err != nil {
+		return nil, err
+	}
+	defer client.Destroy()
+
+	if err = client.Login(); err != nil {
+		return nil, err
+	}
+
+	return client.ExportSession()
+}
@@ -0,0 +1,429 @@
+package steamtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/zergu1ar/steam"
+)
+
+const (
+	loginSecureCookie = "steamLoginSecure"
+	sessionIDCookie   = "sessionid"
+)
+
+func (s *Server) serveQueryTime(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"response": map[string]interface{}{
+			"server_time":            strconv.FormatInt(s.Now().Unix(), 10),
+			"skew_tolerance_seconds": "60",
+			"large_time_jink":        "86400",
+		},
+	})
+}
+
+func (s *Server) serveLoginPage(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: sessionIDCookie, Value: randomHex(12), Path: "/"})
+	writeHTML(w, "<html><body>Sign In</body></html>")
+}
+
+func (s *Server) serveAuthService(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch parts[1] {
+	case "GetPasswordRSAPublicKey":
+		s.getPasswordRSAPublicKey(w, r)
+	case "BeginAuthSessionViaCredentials":
+		s.beginAuthSession(w, r)
+	case "UpdateAuthSessionWithSteamGuardCode":
+		s.updateAuthSession(w, r)
+	case "PollAuthSessionStatus":
+		s.pollAuthSession(w, r)
+	case "GenerateAccessTokenForApp":
+		s.generateAccessToken(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) publicKey() (modulus, exponent string) {
+	return fmt.Sprintf("%x", s.key.N), fmt.Sprintf("%x", s.key.E)
+}
+
+func (s *Server) getPasswordRSAPublicKey(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.accounts[r.Form.Get("account_name")]; !ok {
+		writeResult(w, steam.EResultInvalidParam)
+		return
+	}
+
+	modulus, exponent := s.publicKey()
+	writeJSON(w, map[string]interface{}{
+		"response": map[string]interface{}{
+			"publickey_mod": modulus,
+			"publickey_exp": exponent,
+			"timestamp":     strconv.FormatInt(s.Now().UnixNano(), 10),
+		},
+	})
+}
+
+// checkPassword decrypts an encrypted password sent by the client.
+func (s *Server) checkPassword(account *Account, encrypted string) bool {
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return false
+	}
+
+	password, err := rsa.DecryptPKCS1v15(rand.Reader, s.key, data)
+	if err != nil {
+		return false
+	}
+
+	return string(password) == account.Password
+}
+
+func (s *Server) beginAuthSession(w http.ResponseWriter, r *http.Request) {
+	account, ok := s.accounts[r.Form.Get("account_name")]
+	if !ok || !s.checkPassword(account, r.Form.Get("encrypted_password")) {
+		writeResult(w, steam.EResultInvalidPassword)
+		return
+	}
+
+	session := &authSession{
+		account:   account,
+		requestID: base64.StdEncoding.EncodeToString([]byte(randomHex(8))),
+		guard:     steam.AuthConfirmationNone,
+	}
+
+	switch {
+	case account.SharedSecret != "":
+		session.guard = steam.AuthConfirmationDeviceCode
+	case account.EmailCode != "":
+		session.guard = steam.AuthConfirmationEmailCode
+	default:
+		session.confirmed = true
+	}
+
+	clientID := randomHex(8)
+	s.authSessions[clientID] = session
+
+	message := ""
+	if session.guard == steam.AuthConfirmationEmailCode {
+		message = "example.com"
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"response": map[string]interface{}{
+			"client_id":  clientID,
+			"request_id": session.requestID,
+			"interval":   s.PollInterval.Seconds(),
+			"steamid":    account.SteamID.ToString(),
+			"weak_token": "",
+			"allowed_confirmations": []map[string]interface{}{
+				{"confirmation_type": session.guard, "associated_message": message},
+			},
+		},
+	})
+}
+
+func (s *Server) updateAuthSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.authSessions[r.Form.Get("client_id")]
+	if !ok {
+		writeResult(w, steam.EResultInvalidParam)
+		return
+	}
+
+	code := r.Form.Get("code")
+	switch r.Form.Get("code_type") {
+	case strconv.Itoa(steam.AuthConfirmationDeviceCode):
+		if !s.checkTwoFactorCode(session.account, code) {
+			writeResult(w, steam.EResultTwoFactorCodeMismatch)
+			return
+		}
+	case strconv.Itoa(steam.AuthConfirmationEmailCode):
+		if code != session.account.EmailCode {
+			writeResult(w, steam.EResultInvalidLoginAuthCode)
+			return
+		}
+	default:
+		writeResult(w, steam.EResultInvalidParam)
+		return
+	}
+
+	session.confirmed = true
+	writeResult(w, steam.EResultOK)
+}
+
+// checkTwoFactorCode accepts codes of the current and adjacent periods.
+func (s *Server) checkTwoFactorCode(account *Account, code string) bool {
+	if account.SharedSecret == "" || code == "" {
+		return false
+	}
+
+	now := s.Now().Unix()
+	for _, t := range []int64{now, now - 30, now + 30} {
+		if expected, err := steam.GenerateTwoFactorCode(account.SharedSecret, t); err == nil && expected == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Server) pollAuthSession(w http.ResponseWriter, r *http.Request) {
+	clientID := r.Form.Get("client_id")
+	session, ok := s.authSessions[clientID]
+	if !ok || session.requestID != r.Form.Get("request_id") {
+		writeResult(w, steam.EResultFileNotFound)
+		return
+	}
+
+	if !session.confirmed {
+		writeJSON(w, map[string]interface{}{"response": struct{}{}})
+		return
+	}
+
+	delete(s.authSessions, clientID)
+	writeJSON(w, map[string]interface{}{
+		"response": map[string]interface{}{
+			"refresh_token": s.newToken(session.account, true),
+			"access_token":  s.newToken(session.account, false),
+			"account_name":  session.account.Username,
+		},
+	})
+}
+
+func (s *Server) generateAccessToken(w http.ResponseWriter, r *http.Request) {
+	t := s.validToken(r.Form.Get("refresh_token"), true)
+	if t == nil || t.account.SteamID.ToString() != r.Form.Get("steamid") {
+		writeResult(w, steam.EResultAccessDenied)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"response": map[string]interface{}{
+			"access_token": s.newToken(t.account, false),
+		},
+	})
+}
+
+// newToken issues a JWT shaped token, only its subject, issue and expiry
+// times are meaningful to the client.
+func (s *Server) newToken(account *Account, refresh bool) string {
+	ttl, audience := s.AccessTokenTTL, []string{"web:community"}
+	if refresh {
+		ttl, audience = s.RefreshTokenTTL, []string{"web", "renew", "derive"}
+	}
+	issued := s.Now()
+	expires := issued.Add(ttl)
+
+	header, _ := json.Marshal(map[string]string{"typ": "JWT", "alg": "EdDSA"})
+	payload, _ := json.Marshal(map[string]interface{}{
+		"iss": "steam",
+		"sub": account.SteamID.ToString(),
+		"aud": audience,
+		"exp": expires.Unix(),
+		"iat": issued.Unix(),
+		"jti": randomHex(8),
+	})
+
+	value := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(randomHex(32)))
+
+	s.tokens[value] = &token{account: account, refresh: refresh, expires: expires}
+
+	return value
+}
+
+func (s *Server) validToken(value string, refresh bool) *token {
+	t, ok := s.tokens[value]
+	if !ok || t.refresh != refresh {
+		return nil
+	}
+
+	if s.Now().After(t.expires) {
+		delete(s.tokens, value)
+		return nil
+	}
+
+	return t
+}
+
+func (s *Server) serveFinalizeLogin(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.validToken(r.Form.Get("nonce"), true)
+	if t == nil || r.Form.Get("sessionid") == "" {
+		writeJSON(w, map[string]interface{}{"error": steam.EResultAccessDenied})
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"steamID": t.account.SteamID.ToString(),
+		"redir":   r.Form.Get("redir"),
+		"transfer_info": []map[string]interface{}{
+			{
+				"url":    s.URL + "/login/settoken",
+				"params": map[string]string{"nonce": r.Form.Get("nonce"), "auth": randomHex(16)},
+			},
+		},
+		"primary_domain": "steamcommunity.com",
+	})
+}
+
+// serveSetToken is the transfer target of finalizelogin, it sets the
+// steamLoginSecure cookie carrying a fresh access token.
+func (s *Server) serveSetToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.validToken(r.Form.Get("nonce"), true)
+	if t == nil || t.account.SteamID.ToString() != r.Form.Get("steamID") {
+		writeJSON(w, map[string]interface{}{"result": steam.EResultAccessDenied})
+		return
+	}
+
+	s.setLoginCookie(w, t.account)
+	writeJSON(w, map[string]interface{}{"result": steam.EResultOK})
+}
+
+func (s *Server) setLoginCookie(w http.ResponseWriter, account *Account) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginSecureCookie,
+		Value:    account.SteamID.ToString() + "%7C%7C" + s.newToken(account, false),
+		Path:     "/",
+		HttpOnly: true,
+	})
+}
+
+// sessionAccount returns the account of the web session the request
+// carries, nil when there is none or it expired. s.mu must be held.
+func (s *Server) sessionAccount(r *http.Request) *Account {
+	cookie, err := r.Cookie(loginSecureCookie)
+	if err != nil {
+		return nil
+	}
+
+	value, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return nil
+	}
+
+	parts := strings.SplitN(value, "||", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+
+	t := s.validToken(parts[1], false)
+	if t == nil || t.account.SteamID.ToString() != parts[0] {
+		return nil
+	}
+
+	return t.account
+}
+
+// checkSessionID verifies the sessionid form value against the cookie.
+func checkSessionID(r *http.Request) bool {
+	cookie, err := r.Cookie(sessionIDCookie)
+	return err == nil && cookie.Value != "" && cookie.Value == r.Form.Get("sessionid")
+}
+
+func (s *Server) serveGetRSAKey(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.accounts[r.Form.Get("username")]; !ok {
+		writeJSON(w, map[string]interface{}{"success": false})
+		return
+	}
+
+	modulus, exponent := s.publicKey()
+	writeJSON(w, map[string]interface{}{
+		"success":       true,
+		"publickey_mod": modulus,
+		"publickey_exp": exponent,
+		"timestamp":     strconv.FormatInt(s.Now().UnixNano(), 10),
+		"token_gid":     randomHex(8),
+	})
+}
+
+func (s *Server) serveDoLogin(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.accounts[r.Form.Get("username")]
+	if !ok || !s.checkPassword(account, r.Form.Get("password")) {
+		writeJSON(w, map[string]interface{}{
+			"success": false,
+			"message": "The account name or password that you have entered is incorrect.",
+		})
+		return
+	}
+
+	switch {
+	case account.Captcha != "" && r.Form.Get("captcha_text") != account.Captcha:
+		writeJSON(w, map[string]interface{}{
+			"success":        false,
+			"captcha_needed": true,
+			"captcha_gid":    firstAssetID,
+			"message":        "Please verify your humanity by re-entering the characters below.",
+		})
+		return
+	case account.SharedSecret != "" && !s.checkTwoFactorCode(account, r.Form.Get("twofactorcode")):
+		writeJSON(w, map[string]interface{}{
+			"success":            false,
+			"requires_twofactor": true,
+		})
+		return
+	case account.SharedSecret == "" && account.EmailCode != "" && r.Form.Get("emailauth") != account.EmailCode:
+		writeJSON(w, map[string]interface{}{
+			"success":          false,
+			"emailauth_needed": true,
+			"emaildomain":      "example.com",
+			"emailsteamid":     account.SteamID.ToString(),
+		})
+		return
+	}
+
+	s.setLoginCookie(w, account)
+	writeJSON(w, map[string]interface{}{
+		"success":        true,
+		"login_complete": true,
+		"transfer_parameters": map[string]interface{}{
+			"steamid":      account.SteamID.ToString(),
+			"auth":         randomHex(16),
+			"token_secure": randomHex(16),
+			"webcookie":    randomHex(16),
+		},
+	})
+}
+
+// serveMyProfile redirects to the login page without a session, which is
+// how the client probes whether it is still logged in.
+func (s *Server) serveMyProfile(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	account := s.sessionAccount(r)
+	s.mu.Unlock()
+
+	if account == nil {
+		http.Redirect(w, r, s.URL+"/login/home/?goto=%2Fmy%2F", http.StatusFound)
+		return
+	}
+
+	writeHTML(w, "<html><body>"+account.Username+"</body></html>")
+}
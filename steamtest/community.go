@@ -0,0 +1,318 @@
+package steamtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zergu1ar/steam"
+)
+
+const (
+	inventoryPageSize      = 250
+	inventoryNextPageSize  = 75
+	maxConfirmationSkewSec = 60
+)
+
+func itoa(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+func parseUint(s string) uint64 {
+	n, _ := strconv.ParseUint(s, 10, 64)
+	return n
+}
+
+func (s *Server) serveAPIKey(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	account := s.sessionAccount(r)
+	s.mu.Unlock()
+
+	switch {
+	case account == nil:
+		http.Redirect(w, r, s.URL+"/login", http.StatusFound)
+	case account.Limited || account.APIKey == "":
+		writeHTML(w, "<div id=\"bodyContents_ex\"><h2>Access Denied</h2></div>")
+	default:
+		writeHTML(w, "<div id=\"bodyContents_ex\"><h2>Your Steam Web API Key</h2><p>Key: "+account.APIKey+"</p></div>")
+	}
+}
+
+func (s *Server) serveTradePrivacy(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	account := s.sessionAccount(r)
+	s.mu.Unlock()
+
+	if account == nil {
+		http.Redirect(w, r, s.URL+"/login", http.StatusFound)
+		return
+	}
+
+	writeHTML(w, fmt.Sprintf(
+		"<input class=\"trade_offer_access_url\" id=\"trade_offer_access_url\" value=\"%s/tradeoffer/new/?partner=%d&amp;token=%s\" readonly>",
+		s.URL, account.SteamID.GetAccountID(), account.TradeToken,
+	))
+}
+
+// checkConfirmationKey validates the k parameter of mobileconf requests,
+// it is signed with the identity secret over t and the tag.
+func (s *Server) checkConfirmationKey(r *http.Request) *Account {
+	account, ok := s.bySteamID[steam.SteamID(parseUint(r.Form.Get("a")))]
+	if !ok || account.IdentitySecret == "" {
+		return nil
+	}
+
+	t, err := strconv.ParseInt(r.Form.Get("t"), 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	if skew := s.Now().Unix() - t; skew > maxConfirmationSkewSec || skew < -maxConfirmationSkewSec {
+		return nil
+	}
+
+	key, err := steam.GenerateConfirmationCode(account.IdentitySecret, r.Form.Get("tag"), t)
+	if err != nil || key != r.Form.Get("k") {
+		return nil
+	}
+
+	return account
+}
+
+func (s *Server) serveConfirmations(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sessionAccount(r) == nil {
+		http.Redirect(w, r, "/login?redir=mobileconf", http.StatusFound)
+		return
+	}
+
+	account := s.checkConfirmationKey(r)
+	if account == nil {
+		writeHTML(w, "<div id=\"mobileconf_empty\"><div>Invalid authenticator</div></div>")
+		return
+	}
+
+	var confirmations []*Confirmation
+	for _, confirmation := range s.confirmations {
+		if confirmation.Owner == account.SteamID {
+			confirmations = append(confirmations, confirmation)
+		}
+	}
+	sort.Slice(confirmations, func(i, j int) bool {
+		return confirmations[i].ID < confirmations[j].ID
+	})
+
+	var body strings.Builder
+	body.WriteString("<div id=\"mobileconf_list\">")
+	for _, confirmation := range confirmations {
+		fmt.Fprintf(&body,
+			"<div class=\"mobileconf_list_entry\" data-confid=\"%d\" data-key=\"%d\" data-type=\"%d\" data-creator=\"%d\">"+
+				"<div class=\"mobileconf_list_entry_description\"><div>%s</div><div>%s</div><div>%s</div></div></div>",
+			confirmation.ID, confirmation.Key, confirmation.Type, confirmation.Creator,
+			html.EscapeString(confirmation.Title), html.EscapeString(confirmation.Receiving), html.EscapeString(confirmation.Since),
+		)
+	}
+	body.WriteString("</div>")
+
+	writeHTML(w, body.String())
+}
+
+func (s *Server) serveConfirmationOp(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account := s.checkConfirmationKey(r)
+	if account == nil {
+		writeJSON(w, map[string]interface{}{"success": false, "message": "Invalid authenticator"})
+		return
+	}
+
+	id := parseUint(r.Form.Get("cid"))
+	confirmation, ok := s.confirmations[id]
+	if !ok || confirmation.Owner != account.SteamID || confirmation.Key != parseUint(r.Form.Get("ck")) {
+		writeJSON(w, map[string]interface{}{"success": false, "message": "Confirmation not found"})
+		return
+	}
+
+	state := uint8(steam.TradeStateActive)
+	switch r.Form.Get("op") {
+	case steam.AnswerAllow:
+	case steam.AnswerDeny, "cancel":
+		state = steam.TradeStateCanceledByTwoFactor
+	default:
+		writeJSON(w, map[string]interface{}{"success": false, "message": "Unknown operation"})
+		return
+	}
+
+	delete(s.confirmations, id)
+	if offer, ok := s.offers[confirmation.Creator]; ok && offer.State == steam.TradeStateCreatedNeedsConfirmation {
+		offer.State = state
+		offer.Updated = s.Now()
+	}
+
+	writeJSON(w, map[string]interface{}{"success": true})
+}
+
+// serveInventory answers /inventory/<steamid>/<appid>/<contextid>.
+func (s *Server) serveInventory(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 {
+		http.NotFound(w, r)
+		return
+	}
+
+	key := inventoryKey{
+		owner:     steam.SteamID(parseUint(parts[1])),
+		appID:     parseUint(parts[2]),
+		contextID: parseUint(parts[3]),
+	}
+
+	count := inventoryPageSize
+	if n, err := strconv.Atoi(r.Form.Get("count")); err == nil && n > 0 {
+		count = n
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.bySteamID[key.owner]; !ok {
+		w.WriteHeader(http.StatusForbidden)
+		writeJSON(w, map[string]interface{}{"success": 0, "error": "This profile is private."})
+		return
+	}
+
+	items := s.inventories[key]
+	start := 0
+	if startAssetID := parseUint(r.Form.Get("start_assetid")); startAssetID != 0 {
+		for i, item := range items {
+			if item.AssetID == startAssetID {
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	end := start + count
+	if end > len(items) {
+		end = len(items)
+	}
+
+	assets := make([]map[string]interface{}, 0, end-start)
+	descriptions := make([]*steam.EconItemDesc, 0, end-start)
+	described := make(map[string]bool)
+	for _, item := range items[start:end] {
+		assets = append(assets, map[string]interface{}{
+			"appid":      key.appID,
+			"contextid":  itoa(int64(key.contextID)),
+			"assetid":    strconv.FormatUint(item.AssetID, 10),
+			"classid":    strconv.FormatUint(item.ClassID, 10),
+			"instanceid": strconv.FormatUint(item.InstanceID, 10),
+			"amount":     strconv.FormatUint(item.Amount, 10),
+		})
+
+		descKey := fmt.Sprintf("%d_%d", item.ClassID, item.InstanceID)
+		if described[descKey] {
+			continue
+		}
+		described[descKey] = true
+
+		descriptions = append(descriptions, &steam.EconItemDesc{
+			ClassID:        item.ClassID,
+			InstanceID:     item.InstanceID,
+			Tradable:       !item.Untradable,
+			Name:           item.Name,
+			MarketName:     item.MarketHashName,
+			MarketHashName: item.MarketHashName,
+		})
+	}
+
+	response := map[string]interface{}{
+		"assets":                assets,
+		"descriptions":          descriptions,
+		"success":               1,
+		"total_inventory_count": len(items),
+	}
+	if end < len(items) {
+		response["more_items"] = 1
+		response["last_assetid"] = strconv.FormatUint(items[end-1].AssetID, 10)
+	}
+
+	writeJSON(w, response)
+}
+
+// serveInventoryAppStats renders the g_rgAppContextData variable of the
+// profile inventory page.
+func (s *Server) serveInventoryAppStats(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 {
+		http.NotFound(w, r)
+		return
+	}
+	owner := steam.SteamID(parseUint(parts[1]))
+
+	s.mu.Lock()
+	stats := make(map[string]*steam.InventoryAppStats)
+	for key, items := range s.inventories {
+		if key.owner != owner {
+			continue
+		}
+
+		appID := strconv.FormatUint(key.appID, 10)
+		app, ok := stats[appID]
+		if !ok {
+			app = &steam.InventoryAppStats{
+				AppID:    key.appID,
+				Name:     "App " + appID,
+				Contexts: make(map[string]*steam.InventoryContext),
+			}
+			stats[appID] = app
+		}
+
+		contextID := strconv.FormatUint(key.contextID, 10)
+		app.AssetCount += uint32(len(items))
+		app.Contexts[contextID] = &steam.InventoryContext{
+			ID:         key.contextID,
+			AssetCount: uint32(len(items)),
+			Name:       "Context " + contextID,
+		}
+	}
+	s.mu.Unlock()
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeHTML(w, "<script>\nvar g_rgAppContextData = "+string(data)+";\n</script>")
+}
+
+// takeItem removes an asset from an inventory, nil when it is not there.
+func (s *Server) takeItem(owner steam.SteamID, asset steam.EconItem) *Item {
+	key := inventoryKey{owner, uint64(asset.AppID), asset.ContextID}
+	items := s.inventories[key]
+	for i, item := range items {
+		if item.AssetID == asset.AssetID {
+			s.inventories[key] = append(items[:i:i], items[i+1:]...)
+			return item
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) hasItem(owner steam.SteamID, asset steam.EconItem) bool {
+	for _, item := range s.inventories[inventoryKey{owner, uint64(asset.AppID), asset.ContextID}] {
+		if item.AssetID == asset.AssetID && !item.Untradable {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,442 @@
+package steamtest
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zergu1ar/steam"
+)
+
+// TradeOfferService is an in-memory steam.TradeOfferService for unit tests
+// that do not need HTTP. Err, when set, is consulted before every call and
+// its error returned, e.g. to simulate steam being down.
+type TradeOfferService struct {
+	// NeedsConfirmation makes sent offers wait for a mobile confirmation.
+	NeedsConfirmation bool
+	Err               func(method string, id uint64) error
+
+	mu            sync.Mutex
+	offers        map[uint64]*steam.TradeOffer
+	nextID        uint64
+	confirmations *ConfirmationService
+}
+
+func NewTradeOfferService() *TradeOfferService {
+	return &TradeOfferService{
+		offers: make(map[uint64]*steam.TradeOffer),
+		nextID: firstOfferID,
+	}
+}
+
+// AddOffer stores a copy of offer, assigning an id and TradeStateActive
+// when they are zero.
+func (f *TradeOfferService) AddOffer(offer steam.TradeOffer) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if offer.ID == 0 {
+		offer.ID = f.nextID
+		f.nextID++
+	}
+	if offer.State == 0 {
+		offer.State = steam.TradeStateActive
+	}
+
+	f.offers[offer.ID] = &offer
+	return offer.ID
+}
+
+// LinkConfirmations makes offers that need confirmation show up in
+// confirmations, answering them activates or cancels the offer.
+func (f *TradeOfferService) LinkConfirmations(confirmations *ConfirmationService) {
+	f.mu.Lock()
+	f.confirmations = confirmations
+	f.mu.Unlock()
+
+	confirmations.mu.Lock()
+	confirmations.onAnswer = f.confirm
+	confirmations.mu.Unlock()
+}
+
+func (f *TradeOfferService) confirm(confirmation steam.Confirmation, answer string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	offer, ok := f.offers[confirmation.OfferID]
+	if !ok || offer.State != steam.TradeStateCreatedNeedsConfirmation {
+		return
+	}
+
+	offer.State = steam.TradeStateActive
+	if answer != steam.AnswerAllow {
+		offer.State = steam.TradeStateCanceledByTwoFactor
+	}
+	offer.Updated = time.Now().Unix()
+}
+
+// SetOfferState changes an offer as if the partner acted on it.
+func (f *TradeOfferService) SetOfferState(id uint64, state uint8) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	offer, ok := f.offers[id]
+	if !ok {
+		return false
+	}
+
+	offer.State = state
+	offer.Updated = time.Now().Unix()
+	return true
+}
+
+func (f *TradeOfferService) err(method string, id uint64) error {
+	if f.Err == nil {
+		return nil
+	}
+	return f.Err(method, id)
+}
+
+func (f *TradeOfferService) GetTradeOfferContext(ctx context.Context, id uint64) (*steam.TradeOffer, error) {
+	if err := f.err("GetTradeOffer", id); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	offer, ok := f.offers[id]
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *offer
+	return &copied, nil
+}
+
+func (f *TradeOfferService) GetTradeOffersContext(ctx context.Context, filter uint32, timeCutOff time.Time) (*steam.TradeOfferResponse, error) {
+	if err := f.err("GetTradeOffers", 0); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ids := make([]uint64, 0, len(f.offers))
+	for id := range f.offers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	// the cutoff is only sent to steam when asked for, see
+	// steam.Client.GetTradeOffersContext
+	cutoff := timeCutOff.Unix()
+	if timeCutOff.IsZero() || (filter&steam.TradeFilterHistoricalOnly == 0 && filter&steam.TradeFilterUpdatedSince == 0) {
+		cutoff = time.Now().Unix()
+	}
+
+	response := &steam.TradeOfferResponse{
+		SentOffers:     make([]*steam.TradeOffer, 0),
+		ReceivedOffers: make([]*steam.TradeOffer, 0),
+	}
+	for _, id := range ids {
+		offer := *f.offers[id]
+		active := activeState(offer.State)
+
+		if filter&steam.TradeFilterActiveOnly != 0 && !active && offer.Updated < cutoff {
+			continue
+		}
+		if filter&steam.TradeFilterHistoricalOnly != 0 && (active || offer.Updated < cutoff) {
+			continue
+		}
+
+		if offer.IsOurOffer && filter&steam.TradeFilterSentOffers != 0 {
+			response.SentOffers = append(response.SentOffers, &offer)
+		} else if !offer.IsOurOffer && filter&steam.TradeFilterRecvOffers != 0 {
+			response.ReceivedOffers = append(response.ReceivedOffers, &offer)
+		}
+	}
+
+	return response, nil
+}
+
+func (f *TradeOfferService) SendTradeOfferContext(ctx context.Context, offer *steam.TradeOffer, sid steam.SteamID, token string) error {
+	if err := f.err("SendTradeOffer", 0); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now().Unix()
+	offer.ID = f.nextID
+	f.nextID++
+	offer.Partner = sid.GetAccountID()
+	offer.Created = now
+	offer.Updated = now
+	offer.Expires = now + int64(offerLifetime/time.Second)
+	offer.IsOurOffer = true
+	offer.State = steam.TradeStateActive
+	if f.NeedsConfirmation {
+		offer.State = steam.TradeStateCreatedNeedsConfirmation
+		offer.ConfirmationMethod = steam.TradeConfirmationMobileApp
+	}
+
+	copied := *offer
+	f.offers[offer.ID] = &copied
+
+	if f.NeedsConfirmation && f.confirmations != nil {
+		f.confirmations.AddConfirmation(steam.Confirmation{
+			Title:   "Trade with " + sid.ToString(),
+			OfferID: offer.ID,
+		})
+	}
+
+	return nil
+}
+
+func (f *TradeOfferService) AcceptTradeOfferContext(ctx context.Context, id uint64) error {
+	return f.close("AcceptTradeOffer", id, false, steam.TradeStateAccepted)
+}
+
+func (f *TradeOfferService) DeclineTradeOfferContext(ctx context.Context, id uint64) error {
+	return f.close("DeclineTradeOffer", id, false, steam.TradeStateDeclined)
+}
+
+func (f *TradeOfferService) CancelTradeOfferContext(ctx context.Context, id uint64) error {
+	return f.close("CancelTradeOffer", id, true, steam.TradeStateCanceled)
+}
+
+// close moves an active offer to state, ours tells whether it has to be an
+// offer we sent.
+func (f *TradeOfferService) close(method string, id uint64, ours bool, state uint8) error {
+	if err := f.err(method, id); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	offer, ok := f.offers[id]
+	if !ok || offer.IsOurOffer != ours {
+		return &steam.SteamError{Result: steam.EResultAccessDenied, Endpoint: method}
+	}
+
+	if !activeState(offer.State) {
+		return &steam.SteamError{Result: steam.EResultInvalidState, Endpoint: method}
+	}
+
+	offer.State = state
+	offer.Updated = time.Now().Unix()
+	return nil
+}
+
+// InventoryService is an in-memory steam.InventoryService.
+type InventoryService struct {
+	Err func(method string, sid steam.SteamID) error
+
+	mu    sync.Mutex
+	items map[inventoryKey][]steam.InventoryItem
+}
+
+func NewInventoryService() *InventoryService {
+	return &InventoryService{items: make(map[inventoryKey][]steam.InventoryItem)}
+}
+
+// AddItems appends items to an inventory, items without a description get
+// a tradable one.
+func (f *InventoryService) AddItems(sid steam.SteamID, appID, contextID uint64, items ...steam.InventoryItem) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := inventoryKey{sid, appID, contextID}
+	for _, item := range items {
+		item.AppID = uint32(appID)
+		item.ContextID = contextID
+		if item.Desc == nil {
+			item.Desc = &steam.EconItemDesc{ClassID: item.ClassID, InstanceID: item.InstanceID, Tradable: true}
+		}
+		f.items[key] = append(f.items[key], item)
+	}
+}
+
+func (f *InventoryService) err(method string, sid steam.SteamID) error {
+	if f.Err == nil {
+		return nil
+	}
+	return f.Err(method, sid)
+}
+
+func (f *InventoryService) GetInventoryContext(ctx context.Context, sid steam.SteamID, appID, contextID uint64, tradableOnly bool) ([]steam.InventoryItem, error) {
+	filters := []steam.Filter{}
+	if tradableOnly {
+		filters = append(filters, steam.IsTradable(tradableOnly))
+	}
+
+	return f.GetFilterableInventoryContext(ctx, sid, appID, contextID, filters)
+}
+
+func (f *InventoryService) GetFilterableInventoryContext(ctx context.Context, sid steam.SteamID, appID, contextID uint64, filters []steam.Filter) ([]steam.InventoryItem, error) {
+	if err := f.err("GetInventory", sid); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	items := []steam.InventoryItem{}
+	for _, item := range f.items[inventoryKey{sid, appID, contextID}] {
+		add := true
+		for _, filter := range filters {
+			if add = filter(&item); !add {
+				break
+			}
+		}
+
+		if add {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+func (f *InventoryService) GetInventoryAppStatsContext(ctx context.Context, sid steam.SteamID) (map[string]steam.InventoryAppStats, error) {
+	if err := f.err("GetInventoryAppStats", sid); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stats := make(map[string]steam.InventoryAppStats)
+	for key, items := range f.items {
+		if key.owner != sid {
+			continue
+		}
+
+		appID := strconv.FormatUint(key.appID, 10)
+		app, ok := stats[appID]
+		if !ok {
+			app = steam.InventoryAppStats{
+				AppID:    key.appID,
+				Contexts: make(map[string]*steam.InventoryContext),
+			}
+		}
+
+		app.AssetCount += uint32(len(items))
+		app.Contexts[strconv.FormatUint(key.contextID, 10)] = &steam.InventoryContext{
+			ID:         key.contextID,
+			AssetCount: uint32(len(items)),
+		}
+		stats[appID] = app
+	}
+
+	return stats, nil
+}
+
+// ConfirmationService is an in-memory steam.ConfirmationService. Answered
+// confirmations are removed and their answer recorded.
+type ConfirmationService struct {
+	Err func(method string, id uint64) error
+
+	mu            sync.Mutex
+	confirmations []*steam.Confirmation
+	answers       map[uint64]string
+	nextID        uint64
+	onAnswer      func(confirmation steam.Confirmation, answer string)
+}
+
+func NewConfirmationService() *ConfirmationService {
+	return &ConfirmationService{answers: make(map[uint64]string), nextID: 1}
+}
+
+// AddConfirmation adds a pending confirmation, assigning ID, Key and a
+// trade Type when they are zero.
+func (f *ConfirmationService) AddConfirmation(confirmation steam.Confirmation) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if confirmation.ID == 0 {
+		confirmation.ID = f.nextID
+		f.nextID++
+	}
+	if confirmation.Key == 0 {
+		confirmation.Key = confirmation.ID*7919 + 104729
+	}
+	if confirmation.Type == 0 {
+		confirmation.Type = steam.ConfirmationTypeTrade
+	}
+
+	f.confirmations = append(f.confirmations, &confirmation)
+	return confirmation.ID
+}
+
+// Answer returns the answer given to a confirmation.
+func (f *ConfirmationService) Answer(id uint64) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	answer, ok := f.answers[id]
+	return answer, ok
+}
+
+func (f *ConfirmationService) err(method string, id uint64) error {
+	if f.Err == nil {
+		return nil
+	}
+	return f.Err(method, id)
+}
+
+func (f *ConfirmationService) GetConfirmationsContext(ctx context.Context) ([]*steam.Confirmation, error) {
+	if err := f.err("GetConfirmations", 0); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	confirmations := make([]*steam.Confirmation, 0, len(f.confirmations))
+	for _, confirmation := range f.confirmations {
+		copied := *confirmation
+		confirmations = append(confirmations, &copied)
+	}
+
+	return confirmations, nil
+}
+
+func (f *ConfirmationService) AnswerConfirmationContext(ctx context.Context, confirmation *steam.Confirmation, answer string) error {
+	if err := f.err("AnswerConfirmation", confirmation.ID); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	for i, pending := range f.confirmations {
+		if pending.ID == confirmation.ID && pending.Key == confirmation.Key {
+			f.confirmations = append(f.confirmations[:i], f.confirmations[i+1:]...)
+			f.answers[confirmation.ID] = answer
+			onAnswer := f.onAnswer
+			f.mu.Unlock()
+
+			if onAnswer != nil {
+				onAnswer(*pending, answer)
+			}
+			return nil
+		}
+	}
+	f.mu.Unlock()
+
+	return &steam.SteamError{
+		Result:   steam.EResultFail,
+		Endpoint: "/mobileconf/ajaxop",
+		Message:  "Confirmation not found",
+	}
+}
+
+var (
+	_ steam.TradeOfferService   = (*TradeOfferService)(nil)
+	_ steam.InventoryService    = (*InventoryService)(nil)
+	_ steam.ConfirmationService = (*ConfirmationService)(nil)
+)
@@ -0,0 +1,621 @@
+// Package steamtest provides an in-process fake of the Steam community, Web
+// API and login hosts for testing code built on steam.Client.
+//
+//	srv := steamtest.NewServer()
+//	defer srv.Close()
+//
+//	srv.AddAccount(steamtest.Account{Username: "bot", Password: "secret"})
+//	client, err := srv.Client("bot")
+package steamtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zergu1ar/steam"
+)
+
+const (
+	firstSteamID = steam.SteamID(76561197960265728 + 100000)
+	firstOfferID = 5000000000
+	firstTradeID = 3000000000000000000
+	firstAssetID = 10000000000
+
+	defaultAccessTokenTTL  = 24 * time.Hour
+	defaultRefreshTokenTTL = 200 * 24 * time.Hour
+)
+
+// Account is a steam account known to the server. Zero fields are filled
+// in by AddAccount.
+type Account struct {
+	Username string
+	Password string
+	SteamID  steam.SteamID
+
+	// SharedSecret enables steam guard mobile codes, IdentitySecret mobile
+	// confirmations. Both are base64 encoded like in a maFile.
+	SharedSecret   string
+	IdentitySecret string
+
+	// EmailCode is required at login when the account has no SharedSecret.
+	EmailCode string
+	// Captcha is the text the legacy login asks for before accepting the
+	// password.
+	Captcha string
+
+	APIKey     string
+	TradeToken string
+	// Limited accounts get "Access Denied" on the api key page.
+	Limited bool
+
+	EscrowDays int
+}
+
+// Item is an inventory asset. Untradable items are reported with
+// tradable set to false.
+type Item struct {
+	AssetID        uint64
+	ClassID        uint64
+	InstanceID     uint64
+	Amount         uint64
+	Name           string
+	MarketHashName string
+	Untradable     bool
+}
+
+// Offer is a trade offer between two steam ids.
+type Offer struct {
+	ID        uint64
+	Sender    steam.SteamID
+	Recipient steam.SteamID
+	// ItemsToGive leave the sender's inventory, ItemsToReceive the
+	// recipient's.
+	ItemsToGive    []steam.EconItem
+	ItemsToReceive []steam.EconItem
+	Message        string
+	State          uint8
+	TradeID        uint64
+	Created        time.Time
+	Updated        time.Time
+}
+
+// Confirmation is a pending mobile confirmation. Creator is the id of the
+// trade offer it confirms.
+type Confirmation struct {
+	ID      uint64
+	Key     uint64
+	Owner   steam.SteamID
+	Creator uint64
+	// Type defaults to steam.ConfirmationTypeTrade.
+	Type      uint8
+	Title     string
+	Receiving string
+	Since     string
+}
+
+// Failure is returned instead of the real response, see Server.Fail.
+type Failure struct {
+	// Status defaults to 500, or 200 when only EResult is set.
+	Status  int
+	EResult steam.EResult
+	Body    string
+	// RetryAfter sets the Retry-After header, in seconds.
+	RetryAfter int
+	// Drop closes the connection without a response.
+	Drop bool
+	// Times is the number of requests to fail, 0 means one and a negative
+	// value every request until ClearFailures.
+	Times int
+}
+
+type inventoryKey struct {
+	owner     steam.SteamID
+	appID     uint64
+	contextID uint64
+}
+
+type token struct {
+	account *Account
+	refresh bool
+	expires time.Time
+}
+
+type authSession struct {
+	account   *Account
+	requestID string
+	guard     int
+	confirmed bool
+}
+
+type failure struct {
+	path string
+	Failure
+}
+
+// Server is a fake steam. All state is guarded by one lock and safe to
+// change while clients are running.
+type Server struct {
+	*httptest.Server
+
+	// Now is the server clock used for time sync, guard codes and token
+	// expiry.
+	Now             func() time.Time
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// PollInterval is the interval handed out by BeginAuthSessionViaCredentials.
+	PollInterval time.Duration
+
+	key *rsa.PrivateKey
+
+	mu            sync.Mutex
+	accounts      map[string]*Account
+	bySteamID     map[steam.SteamID]*Account
+	tokens        map[string]*token
+	authSessions  map[string]*authSession
+	inventories   map[inventoryKey][]*Item
+	offers        map[uint64]*Offer
+	receipts      map[uint64][]steam.InventoryItem
+	confirmations map[uint64]*Confirmation
+	failures      []*failure
+	hits          map[string]int
+	nextSteamID   steam.SteamID
+	nextOfferID   uint64
+	nextTradeID   uint64
+	nextAssetID   uint64
+	nextConfID    uint64
+}
+
+// NewServer starts a fake steam, Close it when done.
+func NewServer() *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic("steamtest: " + err.Error())
+	}
+
+	s := &Server{
+		Now:             time.Now,
+		AccessTokenTTL:  defaultAccessTokenTTL,
+		RefreshTokenTTL: defaultRefreshTokenTTL,
+		PollInterval:    10 * time.Millisecond,
+
+		key:           key,
+		accounts:      make(map[string]*Account),
+		bySteamID:     make(map[steam.SteamID]*Account),
+		tokens:        make(map[string]*token),
+		authSessions:  make(map[string]*authSession),
+		inventories:   make(map[inventoryKey][]*Item),
+		offers:        make(map[uint64]*Offer),
+		receipts:      make(map[uint64][]steam.InventoryItem),
+		confirmations: make(map[uint64]*Confirmation),
+		hits:          make(map[string]int),
+		nextSteamID:   firstSteamID,
+		nextOfferID:   firstOfferID,
+		nextTradeID:   firstTradeID,
+		nextAssetID:   firstAssetID,
+		nextConfID:    1,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// Endpoints points every steam host at the server.
+func (s *Server) Endpoints() steam.Endpoints {
+	return steam.Endpoints{
+		Community: s.URL,
+		WebAPI:    s.URL,
+		Store:     s.URL,
+		Login:     s.URL,
+	}
+}
+
+// Credentials returns the credentials of a registered account.
+func (s *Server) Credentials(username string) *steam.Credentials {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.accounts[username]
+	if !ok {
+		return nil
+	}
+
+	return &steam.Credentials{
+		Username:       account.Username,
+		Password:       account.Password,
+		SharedSecret:   account.SharedSecret,
+		IdentitySecret: account.IdentitySecret,
+	}
+}
+
+// Client creates a client for a registered account talking to the server,
+// with rate limits disabled and short retry backoffs. opts are applied
+// after these defaults.
+func (s *Server) Client(username string, opts ...steam.Option) (*steam.Client, error) {
+	credentials := s.Credentials(username)
+	if credentials == nil {
+		credentials = &steam.Credentials{Username: username}
+	}
+
+	defaults := []steam.Option{
+		steam.WithEndpoints(s.Endpoints()),
+		steam.WithHTTPClient(s.Server.Client()),
+		steam.WithRateLimits(nil),
+		steam.WithRetryPolicy(steam.RetryPolicy{
+			MaxAttempts: 3,
+			MinBackoff:  10 * time.Millisecond,
+			MaxBackoff:  100 * time.Millisecond,
+		}),
+	}
+
+	return steam.NewClient(credentials, append(defaults, opts...)...)
+}
+
+// AddAccount registers an account and returns it with the generated
+// steam id, api key and trade token.
+func (s *Server) AddAccount(account Account) Account {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if account.SteamID == 0 {
+		account.SteamID = s.nextSteamID
+		s.nextSteamID++
+	}
+	if account.APIKey == "" && !account.Limited {
+		account.APIKey = strings.ToUpper(randomHex(16))
+	}
+	if account.TradeToken == "" {
+		account.TradeToken = randomHex(4)
+	}
+
+	stored := account
+	s.accounts[account.Username] = &stored
+	s.bySteamID[account.SteamID] = &stored
+
+	return account
+}
+
+// Account returns a copy of a registered account.
+func (s *Server) Account(username string) (Account, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.accounts[username]
+	if !ok {
+		return Account{}, false
+	}
+
+	return *account, true
+}
+
+// ExpireSessions invalidates the access tokens of an account, its web
+// session is rejected until the client renews it with the refresh token.
+func (s *Server) ExpireSessions(username string) {
+	s.revokeTokens(username, false)
+}
+
+// RevokeRefreshTokens invalidates every token of an account so the client
+// has to log in with the password again.
+func (s *Server) RevokeRefreshTokens(username string) {
+	s.revokeTokens(username, true)
+}
+
+func (s *Server) revokeTokens(username string, refresh bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for value, t := range s.tokens {
+		if t.account.Username == username && (refresh || !t.refresh) {
+			delete(s.tokens, value)
+		}
+	}
+}
+
+// AddItems puts items into an inventory, assigning asset ids to items that
+// have none. The stored items are returned.
+func (s *Server) AddItems(owner steam.SteamID, appID, contextID uint64, items ...Item) []Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := inventoryKey{owner, appID, contextID}
+	added := make([]Item, 0, len(items))
+	for _, item := range items {
+		if item.AssetID == 0 {
+			item.AssetID = s.nextAssetID
+			s.nextAssetID++
+		}
+		if item.Amount == 0 {
+			item.Amount = 1
+		}
+		if item.ClassID == 0 {
+			item.ClassID = item.AssetID
+		}
+
+		stored := item
+		s.inventories[key] = append(s.inventories[key], &stored)
+		added = append(added, item)
+	}
+
+	return added
+}
+
+// Inventory returns the items of an inventory.
+func (s *Server) Inventory(owner steam.SteamID, appID, contextID uint64) []Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inventory := s.inventories[inventoryKey{owner, appID, contextID}]
+	items := make([]Item, 0, len(inventory))
+	for _, item := range inventory {
+		items = append(items, *item)
+	}
+
+	return items
+}
+
+// AddOffer stores an offer, e.g. one received by the account under test.
+// A zero ID and State become a new id and TradeStateActive.
+func (s *Server) AddOffer(offer Offer) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addOffer(offer).ID
+}
+
+func (s *Server) addOffer(offer Offer) *Offer {
+	if offer.ID == 0 {
+		offer.ID = s.nextOfferID
+		s.nextOfferID++
+	}
+	if offer.State == 0 {
+		offer.State = steam.TradeStateActive
+	}
+	if offer.Created.IsZero() {
+		offer.Created = s.Now()
+	}
+	if offer.Updated.IsZero() {
+		offer.Updated = offer.Created
+	}
+
+	stored := offer
+	s.offers[offer.ID] = &stored
+
+	return &stored
+}
+
+func (s *Server) Offer(id uint64) (Offer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	offer, ok := s.offers[id]
+	if !ok {
+		return Offer{}, false
+	}
+
+	return *offer, true
+}
+
+// SetOfferState changes the state of an offer as if the partner acted on it.
+func (s *Server) SetOfferState(id uint64, state uint8) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	offer, ok := s.offers[id]
+	if !ok {
+		return false
+	}
+
+	offer.State = state
+	offer.Updated = s.Now()
+
+	return true
+}
+
+// AddConfirmation stores a pending confirmation, zero ID and Key are
+// generated.
+func (s *Server) AddConfirmation(confirmation Confirmation) Confirmation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return *s.addConfirmation(confirmation)
+}
+
+func (s *Server) addConfirmation(confirmation Confirmation) *Confirmation {
+	if confirmation.ID == 0 {
+		confirmation.ID = s.nextConfID
+		s.nextConfID++
+	}
+	if confirmation.Key == 0 {
+		confirmation.Key = confirmation.ID*7919 + 104729
+	}
+	if confirmation.Type == 0 {
+		confirmation.Type = steam.ConfirmationTypeTrade
+	}
+	if confirmation.Since == "" {
+		confirmation.Since = "Just now"
+	}
+
+	stored := confirmation
+	s.confirmations[confirmation.ID] = &stored
+
+	return &stored
+}
+
+// Confirmations returns the pending confirmations of an account.
+func (s *Server) Confirmations(owner steam.SteamID) []Confirmation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var confirmations []Confirmation
+	for _, confirmation := range s.confirmations {
+		if confirmation.Owner == owner {
+			confirmations = append(confirmations, *confirmation)
+		}
+	}
+
+	return confirmations
+}
+
+// Fail makes requests whose path starts with path fail, e.g.
+// "/tradeoffer/new/send" or "/IEconService/".
+func (s *Server) Fail(path string, f Failure) {
+	if f.Times == 0 {
+		f.Times = 1
+	}
+
+	s.mu.Lock()
+	s.failures = append(s.failures, &failure{path: path, Failure: f})
+	s.mu.Unlock()
+}
+
+func (s *Server) ClearFailures() {
+	s.mu.Lock()
+	s.failures = nil
+	s.mu.Unlock()
+}
+
+// Hits returns how many requests were made to path, failed ones included.
+func (s *Server) Hits(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.hits[path]
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.hits[r.URL.Path]++
+	f := s.takeFailure(r.URL.Path)
+	s.mu.Unlock()
+
+	if f != nil {
+		writeFailure(w, f)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/IAuthenticationService/"):
+		s.serveAuthService(w, r)
+	case strings.HasPrefix(path, "/IEconService/"):
+		s.serveEconService(w, r)
+	case path == "/ITwoFactorService/QueryTime/v1/":
+		s.serveQueryTime(w, r)
+	case path == "/jwt/finalizelogin":
+		s.serveFinalizeLogin(w, r)
+	case path == "/login/settoken":
+		s.serveSetToken(w, r)
+	case path == "/login":
+		s.serveLoginPage(w, r)
+	case path == "/login/getrsakey":
+		s.serveGetRSAKey(w, r)
+	case path == "/login/dologin":
+		s.serveDoLogin(w, r)
+	case path == "/my/":
+		s.serveMyProfile(w, r)
+	case path == "/my/tradeoffers/privacy":
+		s.serveTradePrivacy(w, r)
+	case path == "/dev/apikey":
+		s.serveAPIKey(w, r)
+	case path == "/mobileconf/conf":
+		s.serveConfirmations(w, r)
+	case path == "/mobileconf/ajaxop":
+		s.serveConfirmationOp(w, r)
+	case strings.HasPrefix(path, "/inventory/"):
+		s.serveInventory(w, r)
+	case strings.HasPrefix(path, "/profiles/") && strings.HasSuffix(path, "/inventory"):
+		s.serveInventoryAppStats(w, r)
+	case path == "/tradeoffer/new/send":
+		s.serveSendOffer(w, r)
+	case path == "/tradeoffer/new/":
+		s.serveNewOfferPage(w, r)
+	case strings.HasPrefix(path, "/tradeoffer/") && strings.HasSuffix(path, "/accept"):
+		s.serveAcceptOffer(w, r)
+	case strings.HasPrefix(path, "/trade/") && strings.HasSuffix(path, "/receipt"):
+		s.serveReceipt(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// takeFailure returns the failure registered for path, if any.
+func (s *Server) takeFailure(path string) *Failure {
+	for i, f := range s.failures {
+		if !strings.HasPrefix(path, f.path) {
+			continue
+		}
+
+		if f.Times > 0 {
+			if f.Times--; f.Times == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+
+		taken := f.Failure
+		return &taken
+	}
+
+	return nil
+}
+
+func writeFailure(w http.ResponseWriter, f *Failure) {
+	if f.Drop {
+		if hijacker, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+
+	status := f.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+		if f.EResult != 0 {
+			status = http.StatusOK
+		}
+	}
+
+	if f.EResult != 0 {
+		w.Header().Set("X-Eresult", itoa(int64(f.EResult)))
+	}
+	if f.RetryAfter > 0 {
+		w.Header().Set("Retry-After", itoa(int64(f.RetryAfter)))
+	}
+
+	w.WriteHeader(status)
+	w.Write([]byte(f.Body))
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeResult answers a Web API call with an EResult and an empty response.
+func writeResult(w http.ResponseWriter, result steam.EResult) {
+	w.Header().Set("X-Eresult", itoa(int64(result)))
+	writeJSON(w, map[string]interface{}{"response": struct{}{}})
+}
+
+func writeHTML(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(body))
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
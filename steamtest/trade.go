@@ -0,0 +1,455 @@
+package steamtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zergu1ar/steam"
+)
+
+const offerLifetime = 14 * 24 * time.Hour
+
+// offerError mimics the strError steam returns with HTTP 500, ending with
+// the EResult.
+func offerError(w http.ResponseWriter, result steam.EResult) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"strError": fmt.Sprintf("There was an error sending your trade offer.  Please try again later. (%d)", result),
+	})
+}
+
+func (s *Server) serveSendOffer(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account := s.sessionAccount(r)
+	if account == nil || !checkSessionID(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		writeJSON(w, map[string]interface{}{"strError": "You must be logged in."})
+		return
+	}
+
+	partner := steam.SteamID(parseUint(r.Form.Get("partner")))
+	if partner == 0 || partner == account.SteamID {
+		offerError(w, steam.EResultInvalidParam)
+		return
+	}
+
+	if other, ok := s.bySteamID[partner]; ok {
+		var params struct {
+			Token string `json:"trade_offer_access_token"`
+		}
+		json.Unmarshal([]byte(r.Form.Get("trade_offer_create_params")), &params)
+
+		if params.Token != other.TradeToken {
+			offerError(w, steam.EResultAccessDenied)
+			return
+		}
+	}
+
+	var content struct {
+		Me struct {
+			Assets []steam.EconItem `json:"assets"`
+		} `json:"me"`
+		Them struct {
+			Assets []steam.EconItem `json:"assets"`
+		} `json:"them"`
+	}
+	if err := json.Unmarshal([]byte(r.Form.Get("json_tradeoffer")), &content); err != nil {
+		offerError(w, steam.EResultInvalidParam)
+		return
+	}
+
+	if len(content.Me.Assets)+len(content.Them.Assets) == 0 {
+		offerError(w, steam.EResultInvalidParam)
+		return
+	}
+
+	for _, asset := range content.Me.Assets {
+		if !s.hasItem(account.SteamID, asset) {
+			offerError(w, steam.EResultRevoked)
+			return
+		}
+	}
+
+	if _, ok := s.bySteamID[partner]; ok {
+		for _, asset := range content.Them.Assets {
+			if !s.hasItem(partner, asset) {
+				offerError(w, steam.EResultRevoked)
+				return
+			}
+		}
+	}
+
+	needsConfirmation := account.IdentitySecret != "" && len(content.Me.Assets) > 0
+
+	offer := s.addOffer(Offer{
+		Sender:         account.SteamID,
+		Recipient:      partner,
+		ItemsToGive:    content.Me.Assets,
+		ItemsToReceive: content.Them.Assets,
+		Message:        r.Form.Get("tradeoffermessage"),
+	})
+
+	if needsConfirmation {
+		offer.State = steam.TradeStateCreatedNeedsConfirmation
+		s.addConfirmation(Confirmation{
+			Owner:     account.SteamID,
+			Creator:   offer.ID,
+			Title:     "Trade with " + partner.ToString(),
+			Receiving: fmt.Sprintf("You will receive %d items", len(offer.ItemsToReceive)),
+		})
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"tradeofferid":              strconv.FormatUint(offer.ID, 10),
+		"needs_mobile_confirmation": needsConfirmation,
+		"needs_email_confirmation":  false,
+	})
+}
+
+// serveAcceptOffer answers /tradeoffer/<id>/accept and swaps the items.
+func (s *Server) serveAcceptOffer(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account := s.sessionAccount(r)
+	if account == nil || !checkSessionID(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		writeJSON(w, map[string]interface{}{"strError": "You must be logged in."})
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	offer, ok := s.offers[parseUint(parts[1])]
+	if !ok || offer.Recipient != account.SteamID {
+		offerError(w, steam.EResultAccessDenied)
+		return
+	}
+
+	if offer.State != steam.TradeStateActive {
+		offerError(w, steam.EResultInvalidState)
+		return
+	}
+
+	_, senderKnown := s.bySteamID[offer.Sender]
+	for _, asset := range offer.ItemsToReceive {
+		if !s.hasItem(account.SteamID, asset) {
+			offerError(w, steam.EResultRevoked)
+			return
+		}
+	}
+	if senderKnown {
+		for _, asset := range offer.ItemsToGive {
+			if !s.hasItem(offer.Sender, asset) {
+				offer.State = steam.TradeStateInvalidItems
+				offer.Updated = s.Now()
+				offerError(w, steam.EResultRevoked)
+				return
+			}
+		}
+	}
+
+	tradeID := s.nextTradeID
+	s.nextTradeID++
+
+	s.receipts[tradeID] = s.moveItems(offer.Sender, account.SteamID, offer.ItemsToGive, senderKnown)
+	s.moveItems(account.SteamID, offer.Sender, offer.ItemsToReceive, true)
+
+	offer.State = steam.TradeStateAccepted
+	offer.TradeID = tradeID
+	offer.Updated = s.Now()
+
+	writeJSON(w, map[string]interface{}{"tradeid": strconv.FormatUint(tradeID, 10)})
+}
+
+// moveItems transfers assets, giving them new asset ids like steam does,
+// and returns them as the receiver sees them.
+func (s *Server) moveItems(from, to steam.SteamID, assets []steam.EconItem, take bool) []steam.InventoryItem {
+	received := make([]steam.InventoryItem, 0, len(assets))
+	for _, asset := range assets {
+		item := &Item{ClassID: asset.ClassID, InstanceID: asset.InstanceID, Amount: uint64(asset.Amount)}
+		if take {
+			if taken := s.takeItem(from, asset); taken != nil {
+				item = taken
+			}
+		}
+
+		moved := *item
+		moved.AssetID = s.nextAssetID
+		s.nextAssetID++
+		if moved.Amount == 0 {
+			moved.Amount = 1
+		}
+
+		key := inventoryKey{to, uint64(asset.AppID), asset.ContextID}
+		s.inventories[key] = append(s.inventories[key], &moved)
+
+		received = append(received, steam.InventoryItem{
+			AppID:      asset.AppID,
+			ContextID:  asset.ContextID,
+			AssetID:    moved.AssetID,
+			ClassID:    moved.ClassID,
+			InstanceID: moved.InstanceID,
+			Amount:     moved.Amount,
+		})
+	}
+
+	return received
+}
+
+// serveReceipt answers /trade/<tradeid>/receipt with one oItem line per
+// received item.
+func (s *Server) serveReceipt(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	s.mu.Lock()
+	account := s.sessionAccount(r)
+	items, ok := s.receipts[parseUint(parts[1])]
+	s.mu.Unlock()
+
+	if account == nil {
+		http.Redirect(w, r, s.URL+"/login", http.StatusFound)
+		return
+	}
+
+	if !ok {
+		writeHTML(w, "<div id=\"error_msg\">There was a problem loading your trade receipt.</div>")
+		return
+	}
+
+	var body strings.Builder
+	body.WriteString("<script>\n")
+	for _, item := range items {
+		data, _ := json.Marshal(item)
+		fmt.Fprintf(&body, "oItem = %s;\nBuildHover( 'tradeitem', oItem );\n", data)
+	}
+	body.WriteString("</script>")
+
+	writeHTML(w, body.String())
+}
+
+// serveNewOfferPage renders the escrow variables of the new offer page.
+func (s *Server) serveNewOfferPage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account := s.sessionAccount(r)
+	if account == nil {
+		http.Redirect(w, r, s.URL+"/login", http.StatusFound)
+		return
+	}
+
+	var partner *Account
+	accountID := uint32(parseUint(r.Form.Get("partner")))
+	for _, other := range s.accounts {
+		if other.SteamID.GetAccountID() == accountID {
+			partner = other
+		}
+	}
+
+	if partner == nil || partner.TradeToken != r.Form.Get("token") {
+		writeHTML(w, "<div id=\"error_msg\">\n\tThis Trade URL is no longer valid for sending a trade offer to this user.\n</div>")
+		return
+	}
+
+	writeHTML(w, fmt.Sprintf(
+		"<script>\nvar g_daysMyEscrow = %d;\nvar g_daysTheirEscrow = %d;\n</script>",
+		account.EscrowDays, partner.EscrowDays,
+	))
+}
+
+func (s *Server) serveEconService(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account := s.apiKeyAccount(r.Form.Get("key"))
+	if account == nil {
+		w.WriteHeader(http.StatusForbidden)
+		writeHTML(w, "<html><head><title>Forbidden</title></head><body><h1>Forbidden</h1>Access is denied. Retrying will not help. Please verify your <pre>key=</pre> parameter.</body></html>")
+		return
+	}
+
+	switch parts[1] {
+	case "GetTradeOffer":
+		s.getTradeOffer(w, r, account)
+	case "GetTradeOffers":
+		s.getTradeOffers(w, r, account)
+	case "DeclineTradeOffer":
+		s.closeOffer(w, r, account, false)
+	case "CancelTradeOffer":
+		s.closeOffer(w, r, account, true)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) apiKeyAccount(key string) *Account {
+	if key == "" {
+		return nil
+	}
+
+	for _, account := range s.accounts {
+		if account.APIKey == key {
+			return account
+		}
+	}
+
+	return nil
+}
+
+// view renders an offer as the account sees it.
+func (s *Server) view(offer *Offer, account *Account) *steam.TradeOffer {
+	ours := offer.Sender == account.SteamID
+	partner, give, receive := offer.Sender, offer.ItemsToReceive, offer.ItemsToGive
+	if ours {
+		partner, give, receive = offer.Recipient, offer.ItemsToGive, offer.ItemsToReceive
+	}
+
+	view := &steam.TradeOffer{
+		ID:         offer.ID,
+		Partner:    partner.GetAccountID(),
+		ReceiptID:  offer.TradeID,
+		SendItems:  econItems(give),
+		RecvItems:  econItems(receive),
+		Message:    offer.Message,
+		State:      offer.State,
+		Created:    offer.Created.Unix(),
+		Updated:    offer.Updated.Unix(),
+		Expires:    offer.Created.Add(offerLifetime).Unix(),
+		IsOurOffer: ours,
+	}
+	if offer.State == steam.TradeStateCreatedNeedsConfirmation {
+		view.ConfirmationMethod = steam.TradeConfirmationMobileApp
+	}
+
+	return view
+}
+
+func econItems(items []steam.EconItem) []*steam.EconItem {
+	result := make([]*steam.EconItem, 0, len(items))
+	for i := range items {
+		item := items[i]
+		result = append(result, &item)
+	}
+
+	return result
+}
+
+func (s *Server) getTradeOffer(w http.ResponseWriter, r *http.Request, account *Account) {
+	offer, ok := s.offers[parseUint(r.Form.Get("tradeofferid"))]
+	if !ok || (offer.Sender != account.SteamID && offer.Recipient != account.SteamID) {
+		writeJSON(w, map[string]interface{}{"response": struct{}{}})
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"response": map[string]interface{}{"offer": s.view(offer, account)},
+	})
+}
+
+func activeState(state uint8) bool {
+	return state == steam.TradeStateActive ||
+		state == steam.TradeStateCreatedNeedsConfirmation ||
+		state == steam.TradeStateInEscrow
+}
+
+func (s *Server) getTradeOffers(w http.ResponseWriter, r *http.Request, account *Account) {
+	activeOnly := r.Form.Get("active_only") == "1"
+	historicalOnly := r.Form.Get("historical_only") == "1"
+	cutoff, err := strconv.ParseInt(r.Form.Get("time_historical_cutoff"), 10, 64)
+	if err != nil {
+		cutoff = s.Now().Unix()
+	}
+
+	ids := make([]uint64, 0, len(s.offers))
+	for id := range s.offers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	sent := make([]*steam.TradeOffer, 0)
+	received := make([]*steam.TradeOffer, 0)
+	for _, id := range ids {
+		offer := s.offers[id]
+		active := activeState(offer.State)
+
+		if activeOnly && !active && offer.Updated.Unix() < cutoff {
+			continue
+		}
+		if historicalOnly && (active || offer.Updated.Unix() < cutoff) {
+			continue
+		}
+
+		switch account.SteamID {
+		case offer.Sender:
+			if r.Form.Get("get_sent_offers") == "1" {
+				sent = append(sent, s.view(offer, account))
+			}
+		case offer.Recipient:
+			if r.Form.Get("get_received_offers") == "1" {
+				received = append(received, s.view(offer, account))
+			}
+		}
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"response": map[string]interface{}{
+			"trade_offers_sent":     sent,
+			"trade_offers_received": received,
+			"descriptions":          []struct{}{},
+		},
+	})
+}
+
+// closeOffer declines a received or cancels a sent offer.
+func (s *Server) closeOffer(w http.ResponseWriter, r *http.Request, account *Account, cancel bool) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	offer, ok := s.offers[parseUint(r.Form.Get("tradeofferid"))]
+	if !ok {
+		writeResult(w, steam.EResultFileNotFound)
+		return
+	}
+
+	owner, state := offer.Recipient, uint8(steam.TradeStateDeclined)
+	if cancel {
+		owner, state = offer.Sender, steam.TradeStateCanceled
+	}
+
+	if owner != account.SteamID {
+		writeResult(w, steam.EResultAccessDenied)
+		return
+	}
+
+	if !activeState(offer.State) {
+		writeResult(w, steam.EResultInvalidState)
+		return
+	}
+
+	offer.State = state
+	offer.Updated = s.Now()
+
+	for id, confirmation := range s.confirmations {
+		if confirmation.Creator == offer.ID {
+			delete(s.confirmations, id)
+		}
+	}
+
+	writeResult(w, steam.EResultOK)
+}
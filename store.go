@@ -0,0 +1,232 @@
+package steam
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// SessionStore persists exported sessions keyed by account name. Load
+// returns SessionNotFoundError when there is nothing stored for the account.
+type SessionStore interface {
+	Load(account string) ([]byte, error)
+	Save(account string, data []byte) error
+	Delete(account string) error
+}
+
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string][]byte
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string][]byte)}
+}
+
+func (s *MemorySessionStore) Load(account string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.sessions[account]
+	if !ok {
+		return nil, SessionNotFoundError
+	}
+
+	return append([]byte(nil), data...), nil
+}
+
+func (s *MemorySessionStore) Save(account string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[account] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *MemorySessionStore) Delete(account string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, account)
+	return nil
+}
+
+const (
+	sessionFileExt  = ".session"
+	sessionSaltSize = 16
+
+	// sealed files start with the format version and the scrypt cost
+	// parameters, log2(N), r and p, so they can be raised later
+	sealVersion    = 1
+	sealHeaderSize = 4
+	scryptLogN     = 15
+	scryptR        = 8
+	scryptP        = 1
+	sealKeySize    = 32
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// FileSessionStore keeps one file per account in Dir. When a passphrase is
+// set the files are sealed with AES-256-GCM under a key derived with scrypt.
+type FileSessionStore struct {
+	Dir        string
+	passphrase []byte
+}
+
+func NewFileSessionStore(dir, passphrase string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	store := &FileSessionStore{Dir: dir}
+	if passphrase != "" {
+		store.passphrase = []byte(passphrase)
+	}
+
+	return store, nil
+}
+
+func (s *FileSessionStore) path(account string) string {
+	return filepath.Join(s.Dir, unsafeFileChars.ReplaceAllString(account, "_")+sessionFileExt)
+}
+
+func (s *FileSessionStore) Load(account string) ([]byte, error) {
+	data, err := ioutil.ReadFile(s.path(account))
+	if os.IsNotExist(err) {
+		return nil, SessionNotFoundError
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s.passphrase == nil {
+		return data, nil
+	}
+
+	return s.open(data)
+}
+
+func (s *FileSessionStore) Save(account string, data []byte) error {
+	if s.passphrase != nil {
+		sealed, err := s.seal(data)
+		if err != nil {
+			return err
+		}
+		data = sealed
+	}
+
+	tmp, err := ioutil.TempFile(s.Dir, ".session-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path(account))
+}
+
+func (s *FileSessionStore) Delete(account string) error {
+	err := os.Remove(s.path(account))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// aead derives the key for the scrypt parameters in header. The header is
+// only authenticated once the key is derived, so parameters costlier than
+// the ones seal writes are rejected instead of trusted.
+func (s *FileSessionStore) aead(header, salt []byte) (cipher.AEAD, error) {
+	logN, r, p := header[1], int(header[2]), int(header[3])
+	if logN == 0 || logN > scryptLogN || r == 0 || r > scryptR || p == 0 || p > scryptP {
+		return nil, SessionDecryptError
+	}
+
+	key, err := scrypt.Key(s.passphrase, salt, 1<<logN, r, p, sealKeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal returns header | salt | nonce | ciphertext, the header is
+// authenticated with the ciphertext.
+func (s *FileSessionStore) seal(data []byte) ([]byte, error) {
+	header := []byte{sealVersion, scryptLogN, scryptR, scryptP}
+
+	salt := make([]byte, sessionSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := s.aead(header, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	out := append(append(header, salt...), nonce...)
+	return gcm.Seal(out, nonce, data, header), nil
+}
+
+func (s *FileSessionStore) open(data []byte) ([]byte, error) {
+	if len(data) < sealHeaderSize+sessionSaltSize {
+		return nil, SessionDecryptError
+	}
+
+	header := data[:sealHeaderSize]
+	if header[0] != sealVersion {
+		return nil, UnsupportedSessionVersionError
+	}
+
+	gcm, err := s.aead(header, data[sealHeaderSize:sealHeaderSize+sessionSaltSize])
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[sealHeaderSize+sessionSaltSize:]
+	if len(data) < gcm.NonceSize() {
+		return nil, SessionDecryptError
+	}
+
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], header)
+	if err != nil {
+		return nil, SessionDecryptError
+	}
+
+	return plain, nil
+}
@@ -0,0 +1,106 @@
+package steam_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zergu1ar/steam"
+)
+
+func TestFileSessionStoreSealed(t *testing.T) {
+	dir := t.TempDir()
+	store, err := steam.NewFileSessionStore(dir, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte(`{"refresh_token":"secret-token"}`)
+	if err = store.Save("bot", data); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "bot.session")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("mode %v", info.Mode())
+	}
+
+	raw, _ := ioutil.ReadFile(path)
+	if bytes.Contains(raw, []byte("secret-token")) {
+		t.Fatal("session stored in plain text")
+	}
+
+	loaded, err := store.Load("bot")
+	if err != nil || !bytes.Equal(loaded, data) {
+		t.Fatalf("loaded %q, %v", loaded, err)
+	}
+
+	other, _ := steam.NewFileSessionStore(dir, "wrong")
+	if _, err = other.Load("bot"); err != steam.SessionDecryptError {
+		t.Fatalf("wrong passphrase: %v", err)
+	}
+
+	if _, err = store.Load("nobody"); err != steam.SessionNotFoundError {
+		t.Fatalf("missing session: %v", err)
+	}
+}
+
+func TestNewClientRestoresStoredSession(t *testing.T) {
+	srv := newServer(t)
+	addBot(srv, "bot")
+	store := steam.NewMemorySessionStore()
+
+	login(t, srv, "bot", steam.WithSessionStore(store))
+	logins := srv.Hits("/jwt/finalizelogin")
+
+	client, err := srv.Client("bot", steam.WithSessionStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Destroy()
+
+	if client.GetSteamId() == 0 {
+		t.Fatal("stored session not restored")
+	}
+
+	if srv.Hits("/jwt/finalizelogin") != logins {
+		t.Fatal("restoring logged in again")
+	}
+}
+
+func TestFileSessionStoreRejectsCostlyHeader(t *testing.T) {
+	dir := t.TempDir()
+	store, err := steam.NewFileSessionStore(dir, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.Save("bot", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "bot.session")
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// header is version, log2 N, r, p; each of these would make scrypt
+	// allocate gigabytes before the header is authenticated
+	for _, header := range [][]byte{{raw[0], 30, raw[2], raw[3]}, {raw[0], raw[1], 255, raw[3]}, {raw[0], raw[1], raw[2], 255}} {
+		tampered := append(append([]byte(nil), header...), raw[4:]...)
+		if err = ioutil.WriteFile(path, tampered, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = store.Load("bot"); err != steam.SessionDecryptError {
+			t.Fatalf("header %v: got %v, want %v", header, err, steam.SessionDecryptError)
+		}
+	}
+}
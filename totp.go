@@ -1,18 +1,20 @@
 package steam
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
 	"net/http"
+	"net/url"
 )
 
 const (
-	queryTimeUrl = "https://api.steampowered.com/ITwoFactorService/QueryTime/v1/"
-	chars        = "23456789BCDFGHJKMNPQRTVWXY"
-	charsLen     = uint32(len(chars))
+	queryTimePath = "/ITwoFactorService/QueryTime/v1/"
+	chars         = "23456789BCDFGHJKMNPQRTVWXY"
+	charsLen      = uint32(len(chars))
 )
 
 type ServerTimeTip struct {
@@ -74,7 +76,34 @@ func GenerateConfirmationCode(identitySecret, tag string, current int64) (string
 }
 
 func GetTimeTip() (*ServerTimeTip, error) {
-	resp, err := http.Post(queryTimeUrl, "application/x-www-form-urlencoded", nil)
+	return GetTimeTipContext(context.Background())
+}
+
+func GetTimeTipContext(ctx context.Context) (*ServerTimeTip, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, DefaultEndpoints.webAPI(queryTimePath), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeTimeTip(resp)
+}
+
+func (c *Client) queryTimeTip(ctx context.Context) (*ServerTimeTip, error) {
+	resp, err := c.do(ctx, &request{
+		method: http.MethodPost,
+		url:    c.endpoints.webAPI(queryTimePath),
+		form:   url.Values{},
+	})
 	if resp != nil {
 		defer resp.Body.Close()
 	}
@@ -83,14 +112,26 @@ func GetTimeTip() (*ServerTimeTip, error) {
 		return nil, err
 	}
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, newSteamError(resp, "")
+	}
+
+	return decodeTimeTip(resp)
+}
+
+func decodeTimeTip(resp *http.Response) (*ServerTimeTip, error) {
 	type Response struct {
 		Inner *ServerTimeTip `json:"response"`
 	}
 
 	var response Response
-	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		return nil, err
 	}
 
+	if response.Inner == nil {
+		return nil, newSteamError(resp, "empty time tip")
+	}
+
 	return response.Inner, nil
 }
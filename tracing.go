@@ -0,0 +1,27 @@
+package steam
+
+import "context"
+
+// Tracer opens spans around steam operations. args are alternating
+// key-value pairs like the Logger's, see the steamotel package for an
+// OpenTelemetry adapter.
+type Tracer interface {
+	Start(ctx context.Context, name string, args ...interface{}) (context.Context, Span)
+}
+
+type Span interface {
+	SetAttributes(args ...interface{})
+	// End finishes the span, marking it failed when err is not nil.
+	End(err error)
+}
+
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, _ string, _ ...interface{}) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...interface{}) {}
+func (nopSpan) End(error)                    {}
@@ -1,6 +1,7 @@
 package steam
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -38,20 +39,25 @@ const (
 	TradeFilterActiveOnly       = 1 << 3
 	TradeFilterHistoricalOnly   = 1 << 4
 	TradeFilterItemDescriptions = 1 << 5
+	// TradeFilterUpdatedSince makes TradeFilterActiveOnly also return the
+	// offers that changed since timeCutOff.
+	TradeFilterUpdatedSince = 1 << 6
 )
 
 var (
 	//	oItem = {"id":"...",...}; (Javascript code)
-	receiptExp    = regexp.MustCompile(`oItem =\\s(.+?});`)
-	myEscrowExp   = regexp.MustCompile(`var g_daysMyEscrow = (\\d+);`)
-	themEscrowExp = regexp.MustCompile(`var g_daysTheirEscrow = (\\d+);`)
-	errorMsgExp   = regexp.MustCompile(`<div id="error_msg">\\s*([^<]+)\\s*</div>`)
+	receiptExp    = regexp.MustCompile(`oItem =\s(.+?});`)
+	myEscrowExp   = regexp.MustCompile(`var g_daysMyEscrow = (\d+);`)
+	themEscrowExp = regexp.MustCompile(`var g_daysTheirEscrow = (\d+);`)
+	errorMsgExp   = regexp.MustCompile(`<div id="error_msg">\s*([^<]+)\s*</div>`)
 	offerInfoExp  = regexp.MustCompile(`token=([a-zA-Z0-9-_]+)`)
+	// There was an error sending your trade offer. Please try again later. (15)
+	tradeErrorExp = regexp.MustCompile(`\((\d+)\)\s*$`)
 
-	apiGetTradeOffer     = "https://api.steampowered.com/IEconService/GetTradeOffer/v1/?"
-	apiGetTradeOffers    = "https://api.steampowered.com/IEconService/GetTradeOffers/v1/?"
-	apiDeclineTradeOffer = "https://api.steampowered.com/IEconService/DeclineTradeOffer/v1/"
-	apiCancelTradeOffer  = "https://api.steampowered.com/IEconService/CancelTradeOffer/v1/"
+	apiGetTradeOffer     = "/IEconService/GetTradeOffer/v1/?"
+	apiGetTradeOffers    = "/IEconService/GetTradeOffers/v1/?"
+	apiDeclineTradeOffer = "/IEconService/DeclineTradeOffer/v1/"
+	apiCancelTradeOffer  = "/IEconService/CancelTradeOffer/v1/"
 )
 
 type EconItem struct {
@@ -112,10 +118,22 @@ type APIResponse struct {
 }
 
 func (c *Client) GetTradeOffer(id uint64) (*TradeOffer, error) {
-	resp, err := c.client.Get(apiGetTradeOffer + url.Values{
-		"key":          {c.apiKey},
-		"tradeofferid": {strconv.FormatUint(id, 10)},
-	}.Encode())
+	return c.GetTradeOfferContext(context.Background(), id)
+}
+
+func (c *Client) GetTradeOfferContext(ctx context.Context, id uint64) (_ *TradeOffer, err error) {
+	ctx, span := c.tracer.Start(ctx, "steam.GetTradeOffer", "offer_id", id)
+	defer func() {
+		span.End(err)
+	}()
+
+	resp, err := c.do(ctx, &request{
+		method: http.MethodGet,
+		url: c.endpoints.webAPI(apiGetTradeOffer) + url.Values{
+			"key":          {c.apiKey},
+			"tradeofferid": {strconv.FormatUint(id, 10)},
+		}.Encode(),
+	})
 	if resp != nil {
 		defer resp.Body.Close()
 	}
@@ -124,6 +142,10 @@ func (c *Client) GetTradeOffer(id uint64) (*TradeOffer, error) {
 		return nil, err
 	}
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, newSteamError(resp, "")
+	}
+
 	var response APIResponse
 	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		return nil, err
@@ -137,6 +159,10 @@ func testBit(bits uint32, bit uint32) bool {
 }
 
 func (c *Client) GetTradeOffers(filter uint32, timeCutOff time.Time) (*TradeOfferResponse, error) {
+	return c.GetTradeOffersContext(context.Background(), filter, timeCutOff)
+}
+
+func (c *Client) GetTradeOffersContext(ctx context.Context, filter uint32, timeCutOff time.Time) (*TradeOfferResponse, error) {
 	params := url.Values{
 		"key": {c.apiKey},
 	}
@@ -158,10 +184,17 @@ func (c *Client) GetTradeOffers(filter uint32, timeCutOff time.Time) (*TradeOffe
 
 	if testBit(filter, TradeFilterHistoricalOnly) {
 		params.Set("historical_only", "1")
+	}
+
+	// with active_only steam also returns offers updated since the cutoff
+	if testBit(filter, TradeFilterHistoricalOnly) || (testBit(filter, TradeFilterUpdatedSince) && !timeCutOff.IsZero()) {
 		params.Set("time_historical_cutoff", strconv.FormatInt(timeCutOff.Unix(), 10))
 	}
 
-	resp, err := c.client.Get(apiGetTradeOffers + params.Encode())
+	resp, err := c.do(ctx, &request{
+		method: http.MethodGet,
+		url:    c.endpoints.webAPI(apiGetTradeOffers) + params.Encode(),
+	})
 	if resp != nil {
 		defer resp.Body.Close()
 	}
@@ -170,6 +203,10 @@ func (c *Client) GetTradeOffers(filter uint32, timeCutOff time.Time) (*TradeOffe
 		return nil, err
 	}
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, newSteamError(resp, "")
+	}
+
 	var response APIResponse
 	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		return nil, err
@@ -179,7 +216,14 @@ func (c *Client) GetTradeOffers(filter uint32, timeCutOff time.Time) (*TradeOffe
 }
 
 func (c *Client) GetMyTradeToken() (string, error) {
-	resp, err := c.client.Get("https://steamcommunity.com/my/tradeoffers/privacy")
+	return c.GetMyTradeTokenContext(context.Background())
+}
+
+func (c *Client) GetMyTradeTokenContext(ctx context.Context) (string, error) {
+	resp, err := c.do(ctx, &request{
+		method: http.MethodGet,
+		url:    c.endpoints.community("/my/tradeoffers/privacy"),
+	})
 	if resp != nil {
 		defer resp.Body.Close()
 	}
@@ -189,7 +233,7 @@ func (c *Client) GetMyTradeToken() (string, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("http error: %d", resp.StatusCode)
+		return "", newSteamError(resp, "")
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -205,6 +249,13 @@ func (c *Client) GetMyTradeToken() (string, error) {
 	return m[1], nil
 }
 
+func newTradeOfferURL(endpoints Endpoints, sid SteamID, token string) string {
+	return endpoints.community("/tradeoffer/new/?") + url.Values{
+		"partner": {strconv.FormatUint(uint64(sid.GetAccountID()), 10)},
+		"token":   {token},
+	}.Encode()
+}
+
 type EscrowSteamGuardInfo struct {
 	MyDays   int64
 	ThemDays int64
@@ -212,10 +263,14 @@ type EscrowSteamGuardInfo struct {
 }
 
 func (c *Client) GetEscrowGuardInfo(sid SteamID, token string) (*EscrowSteamGuardInfo, error) {
-	resp, err := c.client.Get("https://steamcommunity.com/tradeoffer/new/?" + url.Values{
-		"partner": {strconv.FormatUint(uint64(sid.GetAccountID()), 10)},
-		"token":   {token},
-	}.Encode())
+	return c.GetEscrowGuardInfoContext(context.Background(), sid, token)
+}
+
+func (c *Client) GetEscrowGuardInfoContext(ctx context.Context, sid SteamID, token string) (*EscrowSteamGuardInfo, error) {
+	resp, err := c.do(ctx, &request{
+		method: http.MethodGet,
+		url:    newTradeOfferURL(c.endpoints, sid, token),
+	})
 	if resp != nil {
 		defer resp.Body.Close()
 	}
@@ -225,7 +280,7 @@ func (c *Client) GetEscrowGuardInfo(sid SteamID, token string) (*EscrowSteamGuar
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("http error: %d", resp.StatusCode)
+		return nil, newSteamError(resp, "")
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -260,6 +315,20 @@ func (c *Client) GetEscrowGuardInfo(sid SteamID, token string) (*EscrowSteamGuar
 }
 
 func (c *Client) SendTradeOffer(offer *TradeOffer, sid SteamID, token string) error {
+	return c.SendTradeOfferContext(context.Background(), offer, sid, token)
+}
+
+func (c *Client) SendTradeOfferContext(ctx context.Context, offer *TradeOffer, sid SteamID, token string) (err error) {
+	ctx, span := c.tracer.Start(ctx, "steam.SendTradeOffer",
+		"partner", sid.ToString(),
+		"items_to_give", len(offer.SendItems),
+		"items_to_receive", len(offer.RecvItems),
+	)
+	defer func() {
+		span.SetAttributes("offer_id", offer.ID, "state", int(offer.State))
+		span.End(err)
+	}()
+
 	content := map[string]interface{}{
 		"newversion": true,
 		"version":    3,
@@ -275,33 +344,30 @@ func (c *Client) SendTradeOffer(offer *TradeOffer, sid SteamID, token string) er
 		},
 	}
 
+	session := c.getSession()
+	if session == nil {
+		return InvalidSessionError
+	}
+
 	contentJSON, err := json.Marshal(content)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest(
-		http.MethodPost,
-		"https://steamcommunity.com/tradeoffer/new/send",
-		strings.NewReader(url.Values{
-			"sessionid":                 {c.session.ID},
+	resp, err := c.do(ctx, &request{
+		class:  EndpointTradeOffer,
+		method: http.MethodPost,
+		url:    c.endpoints.community("/tradeoffer/new/send"),
+		form: url.Values{
+			"sessionid":                 {session.ID},
 			"serverid":                  {"1"},
 			"partner":                   {sid.ToString()},
 			"tradeoffermessage":         {offer.Message},
 			"json_tradeoffer":           {string(contentJSON)},
 			"trade_offer_create_params": {"{\"trade_offer_access_token\":\"" + token + "\"}"},
-		}.Encode()),
-	)
-	if err != nil {
-		return err
-	}
-	req.Header.Add("Referer", "https://steamcommunity.com/tradeoffer/new/?"+url.Values{
-		"partner": {strconv.FormatUint(uint64(sid.GetAccountID()), 10)},
-		"token":   {token},
-	}.Encode())
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	resp, err := c.client.Do(req)
+		},
+		referer: newTradeOfferURL(c.endpoints, sid, token),
+	})
 	if resp != nil {
 		defer resp.Body.Close()
 	}
@@ -318,13 +384,22 @@ func (c *Client) SendTradeOffer(offer *TradeOffer, sid SteamID, token string) er
 		EmailDomain                string `json:"email_domain"`
 	}
 
+	// failed sends come with HTTP 500 and a strError, anything else that is
+	// not a 200 (e.g. a 429 html page) has no body worth reading
 	var response Response
 	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return newSteamError(resp, "")
+		}
 		return err
 	}
 
 	if len(response.ErrorMessage) != 0 {
-		return errors.New(response.ErrorMessage)
+		return newTradeOfferError(resp, response.ErrorMessage)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return newSteamError(resp, "")
 	}
 
 	if response.ID == 0 {
@@ -347,11 +422,19 @@ func (c *Client) SendTradeOffer(offer *TradeOffer, sid SteamID, token string) er
 		offer.State = TradeStateActive
 	}
 
+	c.logger.Info("trade offer sent", "offer", offer.ID, "partner", sid, "state", offer.State)
 	return nil
 }
 
 func (c *Client) GetTradeReceivedItems(receiptID uint64) ([]*InventoryItem, error) {
-	resp, err := c.client.Get(fmt.Sprintf("https://steamcommunity.com/trade/%d/receipt", receiptID))
+	return c.GetTradeReceivedItemsContext(context.Background(), receiptID)
+}
+
+func (c *Client) GetTradeReceivedItemsContext(ctx context.Context, receiptID uint64) ([]*InventoryItem, error) {
+	resp, err := c.do(ctx, &request{
+		method: http.MethodGet,
+		url:    c.endpoints.community(fmt.Sprintf("/trade/%d/receipt", receiptID)),
+	})
 	if resp != nil {
 		defer resp.Body.Close()
 	}
@@ -361,7 +444,7 @@ func (c *Client) GetTradeReceivedItems(receiptID uint64) ([]*InventoryItem, erro
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("http error: %d", resp.StatusCode)
+		return nil, newSteamError(resp, "")
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -388,9 +471,22 @@ func (c *Client) GetTradeReceivedItems(receiptID uint64) ([]*InventoryItem, erro
 }
 
 func (c *Client) DeclineTradeOffer(id uint64) error {
-	resp, err := c.client.PostForm(apiDeclineTradeOffer, url.Values{
-		"key":          {c.apiKey},
-		"tradeofferid": {strconv.FormatUint(id, 10)},
+	return c.DeclineTradeOfferContext(context.Background(), id)
+}
+
+func (c *Client) DeclineTradeOfferContext(ctx context.Context, id uint64) (err error) {
+	ctx, span := c.tracer.Start(ctx, "steam.DeclineTradeOffer", "offer_id", id)
+	defer func() {
+		span.End(err)
+	}()
+
+	resp, err := c.do(ctx, &request{
+		method: http.MethodPost,
+		url:    c.endpoints.webAPI(apiDeclineTradeOffer),
+		form: url.Values{
+			"key":          {c.apiKey},
+			"tradeofferid": {strconv.FormatUint(id, 10)},
+		},
 	})
 	if resp != nil {
 		resp.Body.Close()
@@ -400,18 +496,31 @@ func (c *Client) DeclineTradeOffer(id uint64) error {
 		return err
 	}
 
-	result := resp.Header.Get("x-eresult")
-	if result != "1" {
-		return fmt.Errorf("cannot decline trade: %s", result)
+	if resp.Header.Get("x-eresult") != "1" {
+		return newSteamError(resp, "cannot decline trade")
 	}
 
+	c.logger.Info("trade offer declined", "offer", id)
 	return nil
 }
 
 func (c *Client) CancelTradeOffer(id uint64) error {
-	resp, err := c.client.PostForm(apiCancelTradeOffer, url.Values{
-		"key":          {c.apiKey},
-		"tradeofferid": {strconv.FormatUint(id, 10)},
+	return c.CancelTradeOfferContext(context.Background(), id)
+}
+
+func (c *Client) CancelTradeOfferContext(ctx context.Context, id uint64) (err error) {
+	ctx, span := c.tracer.Start(ctx, "steam.CancelTradeOffer", "offer_id", id)
+	defer func() {
+		span.End(err)
+	}()
+
+	resp, err := c.do(ctx, &request{
+		method: http.MethodPost,
+		url:    c.endpoints.webAPI(apiCancelTradeOffer),
+		form: url.Values{
+			"key":          {c.apiKey},
+			"tradeofferid": {strconv.FormatUint(id, 10)},
+		},
 	})
 	if resp != nil {
 		resp.Body.Close()
@@ -421,35 +530,43 @@ func (c *Client) CancelTradeOffer(id uint64) error {
 		return err
 	}
 
-	result := resp.Header.Get("x-eresult")
-	if result != "1" {
-		return fmt.Errorf("cannot cancel trade: %s", result)
+	if resp.Header.Get("x-eresult") != "1" {
+		return newSteamError(resp, "cannot cancel trade")
 	}
 
+	c.logger.Info("trade offer canceled", "offer", id)
 	return nil
 }
 
 func (c *Client) AcceptTradeOffer(id uint64) error {
-	tid := strconv.FormatUint(id, 10)
-	postURL := "https://steamcommunity.com/tradeoffer/" + tid
+	return c.AcceptTradeOfferContext(context.Background(), id)
+}
 
-	req, err := http.NewRequest(
-		http.MethodPost,
-		postURL+"/accept",
-		strings.NewReader(url.Values{
-			"sessionid":    {c.session.ID},
-			"serverid":     {"1"},
-			"tradeofferid": {tid},
-		}.Encode()),
-	)
-	if err != nil {
-		return err
-	}
+func (c *Client) AcceptTradeOfferContext(ctx context.Context, id uint64) (err error) {
+	ctx, span := c.tracer.Start(ctx, "steam.AcceptTradeOffer", "offer_id", id)
+	defer func() {
+		span.End(err)
+	}()
 
-	req.Header.Add("Referer", postURL)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	session := c.getSession()
+	if session == nil {
+		return InvalidSessionError
+	}
 
-	resp, err := c.client.Do(req)
+	tid := strconv.FormatUint(id, 10)
+	postURL := c.endpoints.community("/tradeoffer/" + tid)
+
+	resp, err := c.do(ctx, &request{
+		class:  EndpointTradeOffer,
+		method: http.MethodPost,
+		url:    postURL + "/accept",
+		form: url.Values{
+			"sessionid":    {session.ID},
+			"serverid":     {"1"},
+			"tradeofferid": {tid},
+		},
+		referer: postURL,
+	})
 	if resp != nil {
 		defer resp.Body.Close()
 	}
@@ -458,21 +575,79 @@ func (c *Client) AcceptTradeOffer(id uint64) error {
 		return err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("http error: %d", resp.StatusCode)
-	}
-
 	type Response struct {
 		ErrorMessage string `json:"strError"`
 	}
 
+	// failed accepts come with HTTP 500 and a strError, see SendTradeOffer
 	var response Response
 	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return newSteamError(resp, "")
+		}
 		return err
 	}
 
 	if len(response.ErrorMessage) != 0 {
-		return errors.New(response.ErrorMessage)
+		return newTradeOfferError(resp, response.ErrorMessage)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return newSteamError(resp, "")
+	}
+
+	c.logger.Info("trade offer accepted", "offer", id)
+	return nil
+}
+
+// tradeErrorPhrases classify strError messages that carry no EResult, or
+// one that tradeErrorCause does not map.
+var tradeErrorPhrases = []struct {
+	phrase string
+	cause  error
+}{
+	{"is not available to trade", ErrTargetCannotTrade},
+	{"has a trade ban", ErrTargetCannotTrade},
+	{"you cannot trade with", ErrTargetCannotTrade},
+	{"on trade hold", ErrTradeHold},
+	{"trade offer will be held", ErrTradeHold},
+	{"sent too many trade offers", ErrTradeOfferLimitReached},
+	{"are no longer available", ErrItemsNoLongerAvailable},
+}
+
+// newTradeOfferError parses the EResult steam appends to strError and
+// classifies the error into one of the trade offer sentinel errors, by the
+// EResult when it has a meaning for trade offers and by known phrases
+// otherwise.
+func newTradeOfferError(resp *http.Response, message string) *SteamError {
+	e := newSteamError(resp, message)
+	if m := tradeErrorExp.FindStringSubmatch(message); m != nil {
+		e.Result = parseEResult(m[1])
+	}
+
+	if e.Cause = tradeErrorCause(e.Result); e.Cause != nil {
+		return e
+	}
+
+	lower := strings.ToLower(message)
+	for _, known := range tradeErrorPhrases {
+		if strings.Contains(lower, known.phrase) {
+			e.Cause = known.cause
+			break
+		}
+	}
+
+	return e
+}
+
+func tradeErrorCause(result EResult) error {
+	switch result {
+	case EResultAccessDenied:
+		return ErrTradeOfferAccessDenied
+	case EResultLimitExceeded:
+		return ErrTradeOfferLimitReached
+	case EResultRevoked:
+		return ErrItemsNoLongerAvailable
 	}
 
 	return nil
@@ -0,0 +1,30 @@
+package steam
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestNewTradeOfferErrorCause(t *testing.T) {
+	tests := []struct {
+		message string
+		cause   error
+	}{
+		{"There was an error sending your trade offer.  Please try again later. (26)", ErrItemsNoLongerAvailable},
+		{"There was an error sending your trade offer.  Please try again later. (25)", ErrTradeOfferLimitReached},
+		// the EResult wins over the message
+		{"You cannot trade with Bob because they have a trade ban. (15)", ErrTradeOfferAccessDenied},
+		{"You cannot trade with Bob because they have a trade ban.", ErrTargetCannotTrade},
+		{"One or more of the items in this trade offer are no longer available.", ErrItemsNoLongerAvailable},
+		{"The service is not available right now. (20)", nil},
+		{"Sorry, this feature is not available.", nil},
+	}
+
+	for _, test := range tests {
+		err := newTradeOfferError(&http.Response{StatusCode: http.StatusOK, Header: http.Header{}}, test.message)
+		if test.cause == nil && err.Cause != nil || test.cause != nil && !errors.Is(err, test.cause) {
+			t.Errorf("%q: cause %v, want %v", test.message, err.Cause, test.cause)
+		}
+	}
+}
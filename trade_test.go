@@ -0,0 +1,233 @@
+package steam_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zergu1ar/steam"
+	"github.com/zergu1ar/steam/steamtest"
+)
+
+func TestGetTradeReceivedItems(t *testing.T) {
+	srv := newServer(t)
+	bot := addBot(srv, "bot")
+	partner := srv.AddAccount(steamtest.Account{Username: "partner", Password: "secret"})
+	items := srv.AddItems(partner.SteamID, 730, 2, steamtest.Item{ClassID: 1, InstanceID: 2}, steamtest.Item{ClassID: 3})
+
+	id := srv.AddOffer(steamtest.Offer{
+		Sender:    partner.SteamID,
+		Recipient: bot.SteamID,
+		ItemsToGive: []steam.EconItem{
+			{AppID: 730, ContextID: 2, AssetID: items[0].AssetID, Amount: 1},
+			{AppID: 730, ContextID: 2, AssetID: items[1].AssetID, Amount: 1},
+		},
+	})
+
+	client := login(t, srv, "bot")
+	if err := client.AcceptTradeOffer(id); err != nil {
+		t.Fatal(err)
+	}
+
+	offer, _ := srv.Offer(id)
+	received, err := client.GetTradeReceivedItems(offer.TradeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received) != 2 || received[0].ClassID != 1 || received[0].InstanceID != 2 || received[1].ClassID != 3 {
+		t.Fatalf("received %+v", received)
+	}
+}
+
+func TestGetEscrowGuardInfo(t *testing.T) {
+	srv := newServer(t)
+	addBot(srv, "bot")
+	partner := srv.AddAccount(steamtest.Account{Username: "partner", Password: "secret", EscrowDays: 15})
+
+	client := login(t, srv, "bot")
+	info, err := client.GetEscrowGuardInfo(partner.SteamID, partner.TradeToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.MyDays != 0 || info.ThemDays != 15 || info.ErrorMsg != "" {
+		t.Fatalf("info %+v", info)
+	}
+
+	info, err = client.GetEscrowGuardInfo(partner.SteamID, "wrong")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.ErrorMsg == "" {
+		t.Fatal("expected an error message for a wrong token")
+	}
+}
+
+func TestSendAndConfirmTradeOffer(t *testing.T) {
+	srv := newServer(t)
+	bot := addBot(srv, "bot")
+	partner := srv.AddAccount(steamtest.Account{Username: "partner", Password: "secret"})
+	items := srv.AddItems(bot.SteamID, 730, 2, steamtest.Item{ClassID: 1})
+
+	client := login(t, srv, "bot")
+	fetchAPIKey(t, client)
+
+	offer := &steam.TradeOffer{
+		SendItems: []*steam.EconItem{{AppID: 730, ContextID: 2, AssetID: items[0].AssetID, Amount: 1}},
+	}
+	if err := client.SendTradeOffer(offer, partner.SteamID, partner.TradeToken); err != nil {
+		t.Fatal(err)
+	}
+
+	sent, err := client.GetTradeOffers(steam.TradeFilterSentOffers|steam.TradeFilterActiveOnly, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent.SentOffers) != 1 || sent.SentOffers[0].ID != offer.ID || sent.SentOffers[0].State != steam.TradeStateCreatedNeedsConfirmation {
+		t.Fatalf("sent offers %+v, want offer %d waiting for confirmation", sent.SentOffers, offer.ID)
+	}
+
+	confirmations, err := client.GetConfirmations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(confirmations) != 1 || confirmations[0].OfferID != offer.ID {
+		t.Fatalf("confirmations %+v, want one for offer %d", confirmations, offer.ID)
+	}
+
+	if err = client.AnswerConfirmation(confirmations[0], steam.AnswerAllow); err != nil {
+		t.Fatal(err)
+	}
+
+	if confirmed, _ := srv.Offer(offer.ID); confirmed.State != steam.TradeStateActive {
+		t.Fatalf("offer state %d after confirming, want %d", confirmed.State, steam.TradeStateActive)
+	}
+
+	if err = login(t, srv, "partner").AcceptTradeOffer(offer.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if received := srv.Inventory(partner.SteamID, 730, 2); len(received) != 1 || received[0].ClassID != 1 {
+		t.Fatalf("partner inventory %+v", received)
+	}
+}
+
+func TestGetTradeOffers(t *testing.T) {
+	srv := newServer(t)
+	bot := addBot(srv, "bot")
+	partner := srv.AddAccount(steamtest.Account{Username: "partner", Password: "secret"})
+	items := srv.AddItems(partner.SteamID, 730, 2, steamtest.Item{ClassID: 1})
+
+	active := srv.AddOffer(steamtest.Offer{
+		Sender:      partner.SteamID,
+		Recipient:   bot.SteamID,
+		ItemsToGive: []steam.EconItem{{AppID: 730, ContextID: 2, AssetID: items[0].AssetID, Amount: 1}},
+		Message:     "hi",
+	})
+	declined := srv.AddOffer(steamtest.Offer{
+		Sender:      partner.SteamID,
+		Recipient:   bot.SteamID,
+		ItemsToGive: []steam.EconItem{{AppID: 730, ContextID: 2, AssetID: items[0].AssetID, Amount: 1}},
+		State:       steam.TradeStateDeclined,
+		Updated:     time.Now().Add(-time.Hour),
+	})
+
+	client := login(t, srv, "bot")
+	fetchAPIKey(t, client)
+
+	resp, err := client.GetTradeOffers(steam.TradeFilterRecvOffers|steam.TradeFilterActiveOnly, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.ReceivedOffers) != 1 || resp.ReceivedOffers[0].ID != active || len(resp.SentOffers) != 0 {
+		t.Fatalf("active offers %+v, want only %d", resp.ReceivedOffers, active)
+	}
+
+	offer, err := client.GetTradeOffer(declined)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offer.State != steam.TradeStateDeclined || offer.IsOurOffer || offer.Partner != partner.SteamID.GetAccountID() {
+		t.Fatalf("offer %+v", offer)
+	}
+}
+
+func TestGetTradeOffersUpdatedSince(t *testing.T) {
+	srv := newServer(t)
+	bot := addBot(srv, "bot")
+	partner := srv.AddAccount(steamtest.Account{Username: "partner", Password: "secret"})
+	items := srv.AddItems(partner.SteamID, 730, 2, steamtest.Item{ClassID: 1})
+
+	declined := srv.AddOffer(steamtest.Offer{
+		Sender:      partner.SteamID,
+		Recipient:   bot.SteamID,
+		ItemsToGive: []steam.EconItem{{AppID: 730, ContextID: 2, AssetID: items[0].AssetID, Amount: 1}},
+		State:       steam.TradeStateDeclined,
+		Updated:     time.Now().Add(-time.Hour),
+	})
+
+	client := login(t, srv, "bot")
+	fetchAPIKey(t, client)
+
+	since := time.Now().Add(-2 * time.Hour)
+	resp, err := client.GetTradeOffers(steam.TradeFilterRecvOffers|steam.TradeFilterActiveOnly, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.ReceivedOffers) != 0 {
+		t.Fatalf("active offers %+v, the cutoff is only sent with TradeFilterUpdatedSince", resp.ReceivedOffers)
+	}
+
+	resp, err = client.GetTradeOffers(steam.TradeFilterRecvOffers|steam.TradeFilterActiveOnly|steam.TradeFilterUpdatedSince, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.ReceivedOffers) != 1 || resp.ReceivedOffers[0].ID != declined {
+		t.Fatalf("offers updated since %v: %+v", since, resp.ReceivedOffers)
+	}
+}
+
+func TestSendTradeOfferRateLimited(t *testing.T) {
+	srv := newServer(t)
+	bot := addBot(srv, "bot")
+	partner := srv.AddAccount(steamtest.Account{Username: "partner", Password: "secret"})
+	items := srv.AddItems(bot.SteamID, 730, 2, steamtest.Item{ClassID: 1})
+	client := login(t, srv, "bot")
+
+	srv.Fail("/tradeoffer/new/send", steamtest.Failure{Status: 429, Body: "<html>Too Many Requests</html>", Times: -1})
+	offer := &steam.TradeOffer{
+		SendItems: []*steam.EconItem{{AppID: 730, ContextID: 2, AssetID: items[0].AssetID, Amount: 1}},
+	}
+	if err := client.SendTradeOffer(offer, partner.SteamID, partner.TradeToken); !errors.Is(err, steam.EResultRateLimitExceeded) {
+		t.Fatalf("got %v, want a rate limit error", err)
+	}
+}
+
+func TestAcceptTradeOfferItemsGone(t *testing.T) {
+	srv := newServer(t)
+	bot := addBot(srv, "bot")
+	partner := srv.AddAccount(steamtest.Account{Username: "partner", Password: "secret"})
+	items := srv.AddItems(partner.SteamID, 730, 2, steamtest.Item{ClassID: 1})
+
+	// both offers give the same item, only the first can be accepted
+	var ids []uint64
+	for i := 0; i < 2; i++ {
+		ids = append(ids, srv.AddOffer(steamtest.Offer{
+			Sender:      partner.SteamID,
+			Recipient:   bot.SteamID,
+			ItemsToGive: []steam.EconItem{{AppID: 730, ContextID: 2, AssetID: items[0].AssetID, Amount: 1}},
+		}))
+	}
+
+	client := login(t, srv, "bot")
+	if err := client.AcceptTradeOffer(ids[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	err := client.AcceptTradeOffer(ids[1])
+	if !errors.Is(err, steam.ErrItemsNoLongerAvailable) || !errors.Is(err, steam.EResultRevoked) {
+		t.Fatalf("got %v, want %v", err, steam.ErrItemsNoLongerAvailable)
+	}
+}
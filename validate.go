@@ -1,12 +1,11 @@
 package steam
 
 func validateCredentials(credentials *Credentials) error {
+	if credentials == nil {
+		return CredentialsEmptyError
+	}
 	if credentials.Username == "" {
 		return UsernameEmptyError
 	}
-	if credentials.Password == "" {
-		return PasswordEmptyError
-	}
-
 	return nil
 }
@@ -0,0 +1,307 @@
+package steam
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultWatchInterval = 30 * time.Second
+
+type ConfirmationDecision int
+
+const (
+	// ConfirmationSkip leaves the confirmation to the next rule, or to the
+	// next poll when no rule decides.
+	ConfirmationSkip ConfirmationDecision = iota
+	ConfirmationAccept
+	ConfirmationDeny
+)
+
+// ConfirmationRule decides what to do with a pending confirmation, pending
+// is how long the watcher has seen it.
+type ConfirmationRule func(confirmation *Confirmation, pending time.Duration) ConfirmationDecision
+
+// AcceptOffers accepts trade confirmations whose offer ours reports, e.g.
+// offers registered with TradeOfferManager.TrackOffer.
+func AcceptOffers(ours func(offerID uint64) bool) ConfirmationRule {
+	return func(confirmation *Confirmation, pending time.Duration) ConfirmationDecision {
+		if confirmation.Type == ConfirmationTypeTrade && ours(confirmation.OfferID) {
+			return ConfirmationAccept
+		}
+		return ConfirmationSkip
+	}
+}
+
+// AcceptTrackedOffers accepts trade confirmations of offers tracked by m.
+func AcceptTrackedOffers(m *TradeOfferManager) ConfirmationRule {
+	return AcceptOffers(func(offerID uint64) bool {
+		_, ok := m.OfferMeta(offerID)
+		return ok
+	})
+}
+
+var listingPriceExp = regexp.MustCompile(`\d[\d.,' ]*`)
+
+// AcceptMarketListingsBelow accepts market listings whose buyer price is
+// below limit. Prices are compared in hundredths of the wallet currency
+// whatever steam prints, so $1.23 is 123, $5 is 500 and 1.234,56€ is
+// 123456.
+func AcceptMarketListingsBelow(limit int64) ConfirmationRule {
+	return func(confirmation *Confirmation, pending time.Duration) ConfirmationDecision {
+		if confirmation.Type != ConfirmationTypeMarketListing {
+			return ConfirmationSkip
+		}
+
+		price, ok := listingPrice(confirmation.Receiving)
+		if ok && price < limit {
+			return ConfirmationAccept
+		}
+		return ConfirmationSkip
+	}
+}
+
+// listingPrice parses the first amount of a listing description such as
+// "$1.23 ($1.07)" into hundredths. The last '.' or ',' is the decimal
+// separator when one or two digits follow it, any other separator groups
+// thousands.
+func listingPrice(text string) (int64, bool) {
+	amount := strings.TrimRight(listingPriceExp.FindString(text), ".,' ")
+	if amount == "" {
+		return 0, false
+	}
+
+	whole, fraction := amount, ""
+	if i := strings.LastIndexAny(amount, ".,"); i >= 0 && len(amount)-i-1 <= 2 {
+		whole, fraction = amount[:i], amount[i+1:]
+	}
+
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, whole)
+
+	// pad the fraction so "$5" and "$5.0" are 500 too
+	fraction = (fraction + "00")[:2]
+
+	price, err := strconv.ParseInt(digits+fraction, 10, 64)
+	return price, err == nil
+}
+
+// DenyAfter denies every confirmation pending for at least timeout, put it
+// after the accepting rules.
+func DenyAfter(timeout time.Duration) ConfirmationRule {
+	return func(confirmation *Confirmation, pending time.Duration) ConfirmationDecision {
+		if pending >= timeout {
+			return ConfirmationDeny
+		}
+		return ConfirmationSkip
+	}
+}
+
+type ConfirmationEventType int
+
+const (
+	// ConfirmationSeen is a confirmation listed for the first time.
+	ConfirmationSeen ConfirmationEventType = iota + 1
+	ConfirmationAccepted
+	ConfirmationDenied
+	// ConfirmationFailed is an answer steam rejected, the confirmation is
+	// decided again on the next poll.
+	ConfirmationFailed
+)
+
+var confirmationEventNames = map[ConfirmationEventType]string{
+	ConfirmationSeen:     "ConfirmationSeen",
+	ConfirmationAccepted: "ConfirmationAccepted",
+	ConfirmationDenied:   "ConfirmationDenied",
+	ConfirmationFailed:   "ConfirmationFailed",
+}
+
+func (t ConfirmationEventType) String() string {
+	if name, ok := confirmationEventNames[t]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+type ConfirmationEvent struct {
+	Type         ConfirmationEventType
+	Confirmation *Confirmation
+	// Pending is how long the confirmation had been seen.
+	Pending time.Duration
+	// Err is the answer error of ConfirmationFailed events.
+	Err error
+}
+
+type WatcherOption func(*ConfirmationWatcher)
+
+// WithWatchInterval sets how often Run polls, Run fails with
+// InvalidPollIntervalError unless interval is positive.
+func WithWatchInterval(interval time.Duration) WatcherOption {
+	return func(w *ConfirmationWatcher) {
+		w.interval = interval
+	}
+}
+
+func WithWatcherLogger(logger Logger) WatcherOption {
+	return func(w *ConfirmationWatcher) {
+		w.logger = logger
+	}
+}
+
+// ConfirmationWatcher polls GetConfirmations and answers new confirmations
+// by the first rule that decides. Requests go through the client's
+// mobileconf rate limit. Handlers run on the polling goroutine once the poll
+// is done, they may use the watcher and the services it polls.
+type ConfirmationWatcher struct {
+	service  ConfirmationService
+	rules    []ConfirmationRule
+	handler  func(ConfirmationEvent)
+	interval time.Duration
+	logger   Logger
+	now      func() time.Time
+
+	mu      sync.Mutex
+	seen    map[uint64]time.Time
+	handled map[uint64]bool
+}
+
+func NewConfirmationWatcher(service ConfirmationService, rules []ConfirmationRule, handler func(ConfirmationEvent), opts ...WatcherOption) *ConfirmationWatcher {
+	w := &ConfirmationWatcher{
+		service:  service,
+		rules:    rules,
+		handler:  handler,
+		interval: defaultWatchInterval,
+		logger:   nopLogger{},
+		now:      time.Now,
+		seen:     make(map[uint64]time.Time),
+		handled:  make(map[uint64]bool),
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// Run polls until ctx is done. Failed polls are logged and retried on the
+// next tick.
+func (w *ConfirmationWatcher) Run(ctx context.Context) error {
+	if w.interval <= 0 {
+		return InvalidPollIntervalError
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			w.logger.Warn("confirmation poll failed", "error", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Poll fetches the pending confirmations once and answers those a rule
+// decides. Confirmations already answered are never answered again.
+func (w *ConfirmationWatcher) Poll(ctx context.Context) error {
+	events, err := w.poll(ctx)
+	for _, event := range events {
+		w.emit(event)
+	}
+
+	return err
+}
+
+// poll answers the confirmations and returns the events to fire, they are
+// fired after w.mu is released so handlers can call back into the watcher.
+func (w *ConfirmationWatcher) poll(ctx context.Context) ([]ConfirmationEvent, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	confirmations, err := w.service.GetConfirmationsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := w.now()
+	listed := make(map[uint64]bool, len(confirmations))
+	var events []ConfirmationEvent
+
+	for _, confirmation := range confirmations {
+		listed[confirmation.ID] = true
+		if w.handled[confirmation.ID] {
+			continue
+		}
+
+		first, ok := w.seen[confirmation.ID]
+		if !ok {
+			first = now
+			w.seen[confirmation.ID] = now
+			events = append(events, ConfirmationEvent{Type: ConfirmationSeen, Confirmation: confirmation})
+		}
+		pending := now.Sub(first)
+
+		answer, event := w.decide(confirmation, pending)
+		if answer == "" {
+			continue
+		}
+
+		if err = w.service.AnswerConfirmationContext(ctx, confirmation, answer); err != nil {
+			if ctx.Err() != nil {
+				return events, ctx.Err()
+			}
+			events = append(events, ConfirmationEvent{Type: ConfirmationFailed, Confirmation: confirmation, Pending: pending, Err: err})
+			continue
+		}
+
+		w.handled[confirmation.ID] = true
+		events = append(events, ConfirmationEvent{Type: event, Confirmation: confirmation, Pending: pending})
+	}
+
+	// steam does not list answered confirmations again. Only a fetch that
+	// succeeded gets here, a failed one must not restart the pending timers.
+	for id := range w.seen {
+		if !listed[id] {
+			delete(w.seen, id)
+			delete(w.handled, id)
+		}
+	}
+
+	return events, nil
+}
+
+func (w *ConfirmationWatcher) decide(confirmation *Confirmation, pending time.Duration) (string, ConfirmationEventType) {
+	for _, rule := range w.rules {
+		switch rule(confirmation, pending) {
+		case ConfirmationAccept:
+			return AnswerAllow, ConfirmationAccepted
+		case ConfirmationDeny:
+			return AnswerDeny, ConfirmationDenied
+		}
+	}
+
+	return "", 0
+}
+
+func (w *ConfirmationWatcher) emit(event ConfirmationEvent) {
+	w.logger.Debug("confirmation event", "event", event.Type, "confirmation", event.Confirmation.ID, "offer", event.Confirmation.OfferID, "error", event.Err)
+	if w.handler != nil {
+		w.handler(event)
+	}
+}
@@ -0,0 +1,26 @@
+package steam
+
+import "testing"
+
+func TestListingPrice(t *testing.T) {
+	for text, want := range map[string]int64{
+		"$5":                  500,
+		"$5.00":               500,
+		"$5.5 ($4.79)":        550,
+		"$1.23 ($1.07)":       123,
+		"$1,234.56":           123456,
+		"1.234,56€":           123456,
+		"1 234,56 pуб.":       123456,
+		"CHF 12'345.00":       1234500,
+		"¥ 1,234":             123400,
+		"Sell for 0,03€ each": 3,
+	} {
+		if price, ok := listingPrice(text); !ok || price != want {
+			t.Errorf("listingPrice(%q) = %d, %v, want %d", text, price, ok, want)
+		}
+	}
+
+	if _, ok := listingPrice("free"); ok {
+		t.Error("parsed a price without digits")
+	}
+}
@@ -0,0 +1,120 @@
+package steam_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zergu1ar/steam"
+	"github.com/zergu1ar/steam/steamtest"
+)
+
+func TestWatcherAnswersByRules(t *testing.T) {
+	confirmations := steamtest.NewConfirmationService()
+	ours := confirmations.AddConfirmation(steam.Confirmation{OfferID: 7})
+	theirs := confirmations.AddConfirmation(steam.Confirmation{OfferID: 8})
+	cheap := confirmations.AddConfirmation(steam.Confirmation{Type: steam.ConfirmationTypeMarketListing, Receiving: "$5 ($4.35)"})
+	pricey := confirmations.AddConfirmation(steam.Confirmation{Type: steam.ConfirmationTypeMarketListing, Receiving: "$6.00 ($5.22)"})
+
+	rules := []steam.ConfirmationRule{
+		steam.AcceptOffers(func(offerID uint64) bool { return offerID == 7 }),
+		steam.AcceptMarketListingsBelow(600),
+	}
+
+	events := make(map[uint64][]steam.ConfirmationEventType)
+	w := steam.NewConfirmationWatcher(confirmations, rules, func(event steam.ConfirmationEvent) {
+		events[event.Confirmation.ID] = append(events[event.Confirmation.ID], event.Type)
+	})
+
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []uint64{ours, cheap} {
+		if answer, _ := confirmations.Answer(id); answer != steam.AnswerAllow {
+			t.Errorf("confirmation %d answered %q, want %q", id, answer, steam.AnswerAllow)
+		}
+		if got := events[id]; len(got) != 2 || got[0] != steam.ConfirmationSeen || got[1] != steam.ConfirmationAccepted {
+			t.Errorf("confirmation %d events %v", id, got)
+		}
+	}
+
+	for _, id := range []uint64{theirs, pricey} {
+		if answer, ok := confirmations.Answer(id); ok {
+			t.Errorf("confirmation %d answered %q", id, answer)
+		}
+		if got := events[id]; len(got) != 1 || got[0] != steam.ConfirmationSeen {
+			t.Errorf("confirmation %d events %v", id, got)
+		}
+	}
+}
+
+func TestWatcherHandlerCanPoll(t *testing.T) {
+	confirmations := steamtest.NewConfirmationService()
+	confirmations.AddConfirmation(steam.Confirmation{OfferID: 7})
+
+	var w *steam.ConfirmationWatcher
+	polled := false
+	w = steam.NewConfirmationWatcher(confirmations, nil, func(event steam.ConfirmationEvent) {
+		if !polled {
+			polled = true
+			if err := w.Poll(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- w.Poll(context.Background()) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("poll did not return, handler deadlocked")
+	}
+}
+
+func TestWatcherRunInvalidInterval(t *testing.T) {
+	w := steam.NewConfirmationWatcher(steamtest.NewConfirmationService(), nil, nil, steam.WithWatchInterval(-time.Second))
+	if err := w.Run(context.Background()); err != steam.InvalidPollIntervalError {
+		t.Fatalf("got %v, want %v", err, steam.InvalidPollIntervalError)
+	}
+}
+
+func TestWatcherKeepsPendingTimersAcrossFailedPolls(t *testing.T) {
+	srv := newServer(t)
+	bot := addBot(srv, "bot")
+	id := srv.AddConfirmation(steamtest.Confirmation{Owner: bot.SteamID}).ID
+	client := login(t, srv, "bot")
+
+	var events []steam.ConfirmationEventType
+	w := steam.NewConfirmationWatcher(client, []steam.ConfirmationRule{steam.DenyAfter(150 * time.Millisecond)}, func(event steam.ConfirmationEvent) {
+		events = append(events, event.Type)
+	})
+
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	srv.Fail("/mobileconf/conf", steamtest.Failure{Status: 503, Times: -1})
+	if err := w.Poll(context.Background()); err == nil {
+		t.Fatal("expected the failed fetch to be returned")
+	}
+	srv.ClearFailures()
+
+	time.Sleep(100 * time.Millisecond)
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 || events[0] != steam.ConfirmationSeen || events[1] != steam.ConfirmationDenied {
+		t.Fatalf("events %v, want the confirmation seen once and denied", events)
+	}
+	if remaining := srv.Confirmations(bot.SteamID); len(remaining) != 0 {
+		t.Fatalf("confirmation %d still pending", id)
+	}
+}
@@ -1,13 +1,14 @@
 package steam
 
 import (
+	"context"
 	"io/ioutil"
 	"net/http"
 	"regexp"
 )
 
 const (
-	apiKeyURL           = "https://steamcommunity.com/dev/apikey"
+	apiKeyPath          = "/dev/apikey"
 	accessDeniedPattern = "<h2>Access Denied</h2>"
 )
 
@@ -16,7 +17,14 @@ var (
 )
 
 func (c *Client) GetWebAPIKey() (string, error) {
-	resp, err := c.client.Get(apiKeyURL)
+	return c.GetWebAPIKeyContext(context.Background())
+}
+
+func (c *Client) GetWebAPIKeyContext(ctx context.Context) (string, error) {
+	resp, err := c.do(ctx, &request{
+		method: http.MethodGet,
+		url:    c.endpoints.community(apiKeyPath),
+	})
 	if resp != nil {
 		defer resp.Body.Close()
 	}
@@ -46,5 +54,7 @@ func (c *Client) parseKey(resp *http.Response) (string, error) {
 	}
 
 	c.apiKey = submatch[1]
+	c.saveSession()
+
 	return submatch[1], nil
 }